@@ -0,0 +1,35 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+)
+
+// Alternative describes a compliant variant of a data use document
+// as a diff against the original document
+type Alternative struct {
+	Dropped        []structs.Statement `json:"dropped"`                  // statements of the original which are not in the variant
+	Document       *structs.Document   `json:"document,omitempty"`       // the variant as the author would write it, see Normalizer.Denormalize
+	DroppedClauses []DroppedClause     `json:"droppedClauses,omitempty"` // the clauses of the author which are not in the variant
+}
+
+// DiffDocuments returns the Alternative describing the variant of the original
+// document. Statements are identified by their tracking ids, so for documents
+// with and/except clauses the dropped statements are the unfolded sub-statements,
+// with the suffixed tracking ids assigned by the Normalizer.
+func DiffDocuments(original *NormalizedDocument, variant *NormalizedDocument) Alternative {
+	kept := make(map[string]bool)
+	for _, s := range variant.Statements {
+		kept[s.TrackingID] = true
+	}
+	alt := Alternative{Dropped: []structs.Statement{}}
+	for _, s := range original.Statements {
+		if !kept[s.TrackingID] {
+			alt.Dropped = append(alt.Dropped, s.Statement)
+		}
+	}
+	return alt
+}
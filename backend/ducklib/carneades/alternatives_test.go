@@ -0,0 +1,42 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+)
+
+func normalizedDoc(ids ...string) *NormalizedDocument {
+	d := &NormalizedDocument{}
+	for _, id := range ids {
+		d.Statements = append(d.Statements, NormalizedStatement{Statement: structs.Statement{TrackingID: id}})
+	}
+	return d
+}
+
+func TestDiffDocuments(t *testing.T) {
+	tests := []struct {
+		name     string
+		original *NormalizedDocument
+		variant  *NormalizedDocument
+		want     []string
+	}{
+		{"nothing dropped", normalizedDoc("a", "b"), normalizedDoc("a", "b"), []string{}},
+		{"one dropped", normalizedDoc("a", "b", "c"), normalizedDoc("a", "c"), []string{"b"}},
+		{"all dropped", normalizedDoc("a", "b"), normalizedDoc(), []string{"a", "b"}},
+		{"sub-statements", normalizedDoc("a-0", "a-1", "b"), normalizedDoc("a-1", "b"), []string{"a-0"}},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for _, s := range DiffDocuments(tt.original, tt.variant).Dropped {
+			got = append(got, s.TrackingID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q. DiffDocuments() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
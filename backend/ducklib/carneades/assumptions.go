@@ -0,0 +1,134 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+	"github.com/carneades/carneades-4/src/engine/caes"
+	"github.com/carneades/carneades-4/src/engine/terms"
+)
+
+// assumptionSetKey is the key of the metadata of the statements of an argument graph
+// which are facts of an assumption set, with the id of the assumption set as value
+const assumptionSetKey = "assumptionSet"
+
+// ParseFact parses a fact of an assumption set, which must be a ground atomic formula,
+// e.g. isInEu(custom_region), and returns it in the form used for the statements of
+// argument graphs
+func ParseFact(fact string) (string, error) {
+	t, ok := readAtomicFormula(fact)
+	if !ok {
+		return "", structs.NewHTTPError(fmt.Sprintf("The fact %s is not an atomic formula", fact), http.StatusBadRequest)
+	}
+	if !terms.Ground(t, nil) {
+		return "", structs.NewHTTPError(fmt.Sprintf("The fact %s contains variables", fact), http.StatusBadRequest)
+	}
+	return t.String(), nil
+}
+
+// readAtomicFormula parses an atomic formula, which may end with a period
+func readAtomicFormula(src string) (terms.Term, bool) {
+	t, ok := terms.ReadString(strings.TrimSuffix(strings.TrimSpace(src), "."))
+	// the parser accepts an unclosed argument list, returning a compound term without arguments
+	if c, isCompound := t.(terms.Compound); isCompound && len(c.Args) == 0 {
+		return nil, false
+	}
+	return t, ok && terms.AtomicFormula(t)
+}
+
+// NormalizeAssumptionSet parses the facts of the assumption set, replacing them with
+// their normalized form and removing duplicates. An error is returned for the first
+// fact which is not a ground atomic formula.
+func NormalizeAssumptionSet(set *structs.AssumptionSet) error {
+	facts := make([]string, 0, len(set.Facts))
+	seen := make(map[string]bool, len(set.Facts))
+	for _, f := range set.Facts {
+		fact, err := ParseFact(f)
+		if err != nil {
+			return err
+		}
+		if !seen[fact] {
+			seen[fact] = true
+			facts = append(facts, fact)
+		}
+	}
+	set.Facts = facts
+	return nil
+}
+
+// assume adds the facts of the assumption sets to the assumptions of the argument
+// graph, marking their statements with the id of their assumption set
+func assume(ag *caes.ArgGraph, sets []structs.AssumptionSet) {
+	for _, set := range sets {
+		for _, f := range set.Facts {
+			fact, err := ParseFact(f)
+			if err != nil {
+				// stored sets are validated, so the fact was changed in the database
+				continue
+			}
+			if stmt, ok := ag.Statements[fact]; ok {
+				stmt.Metadata[assumptionSetKey] = set.ID
+				continue
+			}
+			ag.Statements[fact] = &caes.Statement{
+				Id:       fact,
+				Metadata: map[string]interface{}{assumptionSetKey: set.ID},
+				Text:     fact,
+				Args:     []*caes.Argument{}}
+			ag.Assumptions = append(ag.Assumptions, fact)
+		}
+	}
+}
+
+// reliedAssumptionSets returns the ids of the assumption sets the label of the statement
+// relies on, in order, or nil if it relies on none. An in statement relies on the sets of its own fact and of the
+// premises of its applicable arguments, a statement which is not in relies on the sets
+// the in positions of its issue rely on.
+func reliedAssumptionSets(stmt *caes.Statement) []string {
+	sets := make(map[string]bool)
+	collectAssumptionSets(stmt, sets, make(map[*caes.Statement]bool))
+	if len(sets) == 0 {
+		return nil
+	}
+	l := make([]string, 0, len(sets))
+	for id := range sets {
+		l = append(l, id)
+	}
+	sort.Strings(l)
+	return l
+}
+
+func collectAssumptionSets(stmt *caes.Statement, sets map[string]bool, visited map[*caes.Statement]bool) {
+	if stmt == nil || visited[stmt] {
+		return
+	}
+	visited[stmt] = true
+	if id, ok := stmt.Metadata[assumptionSetKey].(string); ok {
+		sets[id] = true
+	}
+	if stmt.Label != caes.In {
+		if stmt.Issue != nil {
+			for _, pos := range stmt.Issue.Positions {
+				if pos != stmt && pos.Label == caes.In {
+					collectAssumptionSets(pos, sets, visited)
+				}
+			}
+		}
+		return
+	}
+	for _, arg := range stmt.Args {
+		if arg.Weight <= 0 {
+			continue
+		}
+		for _, p := range arg.Premises {
+			collectAssumptionSets(p.Stmt, sets, visited)
+		}
+	}
+}
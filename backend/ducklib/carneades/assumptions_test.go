@@ -0,0 +1,237 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+	"github.com/Microsoft/DUCK/backend/ducklib/taxonomy"
+	"github.com/carneades/carneades-4/src/engine/caes"
+)
+
+// testAssumptionRuleBase requires consent for collecting data, if the
+// data subjects are residents of the EU
+var testAssumptionRuleBase = strings.NewReplacer(
+	"  notDocConsentRequired/0: notDocConsentRequired\n", `  notDocConsentRequired/0: notDocConsentRequired
+  euResidents/0: euResidents
+`,
+	"argument_schemes:\n", `argument_schemes:
+  - id: collect
+    variables: [US,USL,Q,DC,SS,SSL,RS,RSL,ID,P,PA]
+    premises:
+      - dataUseStatement(dus(US,USL,Q,DC,SS,SSL,collect,RS,RSL,ID,P,PA))
+      - euResidents
+    conclusions:
+      - consentRequired(dus(US,USL,Q,DC,SS,SSL,collect,RS,RSL,ID,P,PA))
+
+`).Replace(testRuleBase)
+
+func TestParseFact(t *testing.T) {
+	tests := []struct {
+		name       string
+		fact       string
+		want       string
+		wantStatus int
+	}{
+		{"constant", "euResidents", "euResidents", 0},
+		{"compound", " isInEu(custom_region). ", "isInEu(custom_region)", 0},
+		{"variable", "isInEu(X)", "", http.StatusBadRequest},
+		{"rule", "a :- b", "", http.StatusBadRequest},
+		{"invalid", "isInEu(", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		got, err := ParseFact(tt.fact)
+		if httpStatus(err) != tt.wantStatus {
+			t.Errorf("%q. ParseFact() error = %v, want status %v", tt.name, err, tt.wantStatus)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q. ParseFact() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeAssumptionSet(t *testing.T) {
+	set := structs.AssumptionSet{Facts: []string{"euResidents.", "isInEu(x)", "euResidents"}}
+	if err := NormalizeAssumptionSet(&set); err != nil {
+		t.Fatalf("NormalizeAssumptionSet() error = %v", err)
+	}
+	if want := []string{"euResidents", "isInEu(x)"}; !reflect.DeepEqual(set.Facts, want) {
+		t.Errorf("NormalizeAssumptionSet() facts = %v, want %v", set.Facts, want)
+	}
+	set.Facts = append(set.Facts, "isInEu(X)")
+	if err := NormalizeAssumptionSet(&set); httpStatus(err) != http.StatusBadRequest {
+		t.Errorf("NormalizeAssumptionSet() error = %v, want status %v", err, http.StatusBadRequest)
+	}
+}
+
+func TestComplianceCheckerPlugin_assumptionSets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "duckRulebases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "test.yml"), []byte(testAssumptionRuleBase), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := MakeComplianceCheckerPlugin(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Intialize(); err != nil {
+		t.Fatalf("Intialize() error = %v", err)
+	}
+
+	// without the assumption the document is compliant
+	ok, _, err := c.IsCompliant("test", actionDoc("collect", "provide"))
+	if !ok || err != nil {
+		t.Fatalf("IsCompliant() = %v, %v, want true", ok, err)
+	}
+
+	doc := actionDoc("collect", "provide")
+	doc.AssumptionSets = []structs.AssumptionSet{
+		{ID: "eu", Facts: []string{"euResidents"}},
+		{ID: "other", Facts: []string{"isInEu(custom_region)"}},
+	}
+	ok, expl, err := c.IsCompliant("test", doc)
+	if ok || err != nil {
+		t.Fatalf("IsCompliant() with assumptions = %v, %v, want false", ok, err)
+	}
+	if got := expl["s0"]["consentRequired"]; !got.Value || !reflect.DeepEqual(got.AssumptionSets, []string{"eu"}) {
+		t.Errorf("IsCompliant() s0 consentRequired = %+v, want relying on [eu]", got)
+	}
+	if got := expl["s1"]["consentRequired"]; got.AssumptionSets != nil {
+		t.Errorf("IsCompliant() s1 consentRequired = %+v, want relying on no assumption set", got)
+	}
+}
+
+func TestComplianceChecker_taxonomyFacts(t *testing.T) {
+	tax, err := taxonomy.Load(filepath.Join("..", "..", "..", "frontend", "src", "assets", "config"), taxonomy.DefaultLocale)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	c := MakeComplianceChecker()
+	theory := testRb2Theory(t, c)
+	doc := actionDoc("provide")
+	doc.Statements[0].UseScopeLocation = "de"
+
+	// rb2 derives the locations in the EU from the regions of the taxonomy
+	for _, facts := range [][]string{nil, tax.Facts()} {
+		doc.Facts = facts
+		ag, err := c.ArgumentGraph(theory, doc)
+		if err != nil {
+			t.Fatalf("ArgumentGraph() error = %v", err)
+		}
+		stmt, ok := ag.Statements["isInEu(de)"]
+		if got, want := ok && stmt.Label == caes.In, facts != nil; got != want {
+			t.Errorf("isInEu(de) with %v facts in = %v, want %v", len(facts), got, want)
+		}
+	}
+}
+
+// rb2Legacy returns the source of rb2 with the rules it had for the locations in the EU and
+// the equivalent scopes before they were derived from the taxonomy, in place of the rules
+// using the derived facts, since the order of the rules matters to the inference
+func rb2Legacy(src string) (string, error) {
+	var isInEu, equivalentScope strings.Builder
+	for i, l := range strings.Fields("eu at be bg hr cy cz dk ee fi fr de gr hu ie it lv lt lu mt nl pl pt ro sk si es se") {
+		fmt.Fprintf(&isInEu, "  - id: isEu%02d\n    conclusions:\n      - isInEu(%s)\n", i+1, l)
+	}
+	for i, scopes := range [][2]string{
+		{"capability", "service"},
+		{"services_agreement", "csp_services"},
+		{"third_party_partners", "third_party_services"},
+		{"third_party_services", "third_party_partners"},
+	} {
+		fmt.Fprintf(&equivalentScope, "  - id: equivalentScope%d\n    variables: [S1,S2]\n    premises:\n"+
+			"      - resultScope(S1,%s)\n      - resultScope(S2,%s)\n    conclusions:\n      - equivalentScope(S1,S2)\n\n", i+1, scopes[0], scopes[1])
+	}
+	for _, r := range []struct{ from, to, legacy string }{
+		{"  - id: isEu01\n", "  # adequacy protection as EU", isInEu.String()},
+		{"  - id: equivalentScope1\n", "  # smallerOrEqualScope Rules", equivalentScope.String()},
+	} {
+		i, j := strings.Index(src, r.from), strings.Index(src, r.to)
+		if i < 0 || j < i {
+			return "", fmt.Errorf("rb2 has no rules from %q to %q", r.from, r.to)
+		}
+		src = src[:i] + r.legacy + src[j:]
+	}
+	return src, nil
+}
+
+func TestComplianceChecker_taxonomyFactsUnchangedVerdicts(t *testing.T) {
+	tax, err := taxonomy.Load(filepath.Join("..", "..", "..", "frontend", "src", "assets", "config"), taxonomy.DefaultLocale)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	src, err := ioutil.ReadFile(rb2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := MakeComplianceChecker()
+	current := testRb2Theory(t, c)
+	legacySrc, err := rb2Legacy(string(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := c.GetTheory("legacy", "1", strings.NewReader(legacySrc))
+	if err != nil {
+		t.Fatalf("GetTheory() error = %v", err)
+	}
+	// the legacy rules are checked without the facts replacing them
+	legacyFacts := []string{}
+	for _, f := range tax.Facts() {
+		if !strings.HasPrefix(f, "inRegion(") && !strings.HasPrefix(f, "equivalentScopeCode(") {
+			legacyFacts = append(legacyFacts, f)
+		}
+	}
+
+	docs := map[string]*NormalizedDocument{}
+	for _, l := range []string{"eu", "at", "de", "fr", "se", "us", "ch", "cn"} {
+		doc := actionDoc("provide", "market")
+		doc.Statements[0].UseScopeLocation = l
+		doc.Statements[1].ResultScopeLocation = l
+		docs["location "+l] = doc
+	}
+	scopes := []string{"capability", "service", "services_agreement", "csp_services", "csp_products", "third_party_services", "third_party_partners"}
+	for _, s1 := range scopes {
+		for _, s2 := range scopes {
+			doc := actionDoc("provide", "market")
+			doc.Statements[0].ResultScopeCode = s1
+			doc.Statements[1].ResultScopeCode = s2
+			docs["scopes "+s1+" and "+s2] = doc
+		}
+	}
+
+	for name, doc := range docs {
+		doc.Facts = legacyFacts
+		want, err := c.Check(legacy, doc)
+		if err != nil {
+			t.Fatalf("%q. Check() legacy error = %v", name, err)
+		}
+		doc.Facts = tax.Facts()
+		got, err := c.Check(current, doc)
+		if err != nil {
+			t.Fatalf("%q. Check() error = %v", name, err)
+		}
+		if got.Verdict != want.Verdict {
+			t.Errorf("%q. Check() verdict = %v, want %v", name, got.Verdict, want.Verdict)
+		}
+		for id, e := range want.Explanation {
+			for p, v := range e {
+				if g := got.Explanation[id][p]; g.Value != v.Value || g.Assumed != v.Assumed {
+					t.Errorf("%q. Check() %s %s = %v, want %v", name, id, p, g.Value, v.Value)
+				}
+			}
+		}
+	}
+}
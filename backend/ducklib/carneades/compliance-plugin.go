@@ -5,12 +5,18 @@
 package carneades
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"fmt"
-	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sync"
 
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+	"github.com/carneades/carneades-4/src/engine/caes"
 	"gopkg.in/yaml.v2"
 	// "path/filepath"
 )
@@ -22,6 +28,8 @@ type RuleBaseDescription struct {
 	Version     string `yaml:"version"`
 	Title       string `yaml:"title"`
 	Description string `yaml:"description"`
+	Revision    string `yaml:"-"` // hash of the content of the rulebase file
+	source      []byte // the content of the rulebase file the revision was accepted with
 }
 
 // ComplianceCheckerPlugin maps to a ComplianceChecker and RuleBase Descriptions
@@ -29,6 +37,8 @@ type ComplianceCheckerPlugin struct {
 	checker     *ComplianceChecker
 	RuleBaseDir string
 	RuleBases   map[string]RuleBaseDescription // RuleBaseDescription.Id -> RuleBaseDescription
+	lock        sync.Mutex                     // guards RuleBases and the rulebase files
+	sessions    *sessionCache
 }
 
 // MakeComplianceCheckerPlugin returns an error if the ruleBase dir does not
@@ -45,13 +55,14 @@ func MakeComplianceCheckerPlugin(ruleBaseDir string) (*ComplianceCheckerPlugin,
 	if !i.IsDir() {
 		return nil, fmt.Errorf("ruleBaseDir %s is not a directory", ruleBaseDir)
 	}
-	return &ComplianceCheckerPlugin{MakeComplianceChecker(), ruleBaseDir, make(map[string]RuleBaseDescription)}, nil
+	return &ComplianceCheckerPlugin{checker: MakeComplianceChecker(), RuleBaseDir: ruleBaseDir, RuleBases: make(map[string]RuleBaseDescription), sessions: newSessionCache()}, nil
 }
 
-// Intialize For each file in RuleBaseDir:
+// Intialize For each file in RuleBaseDir, except rulebase test files:
 //    1. Parse the YAML and extract the id, version, title and description
 //    2. Call checker.GetTheory function to compile each rulebase into a
-//       Carneades theory and cache the theory.
+//       Carneades theory and cache the theory, using the hash of the
+//       content of the file as the revision of the theory.
 //    3. Create a RuleBaseDescription and add it to the RuleBases map, indexed
 //       by its Id.
 //  Return an error if any rulebase cannot be compiled into a Theory
@@ -61,78 +72,242 @@ func (c *ComplianceCheckerPlugin) Intialize() error {
 		return err
 	}
 
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	for _, file := range files {
-		if !file.IsDir() {
-			fr, err := os.Open(filepath.Join(c.RuleBaseDir, file.Name()))
-			defer fr.Close()
+		if !file.IsDir() && !IsRuleBaseTestFile(file.Name()) {
+			dat, err := ioutil.ReadFile(filepath.Join(c.RuleBaseDir, file.Name()))
 			if err != nil {
 				return err
 			}
-
-			//create RuleBaseDescription
-			type rb struct {
-				Meta RuleBaseDescription
-			}
-			rby := rb{}
-			//read file & Unmarshal content into rby
-			dat, err := ioutil.ReadAll(fr)
-			if err != nil {
-				return err
-			}
-
-			err = yaml.Unmarshal(dat, &rby)
+			desc, err := parseRuleBaseDescription(dat)
 			if err != nil {
 				return err
 			}
-			desc := rby.Meta
 			desc.Filename = file.Name()
-			// compile theory, we dont use it here, it will be cached
-			fr, err = os.Open(filepath.Join(c.RuleBaseDir, file.Name()))
-
-			if err != nil {
-				return err
-			}
-			_, err = c.checker.GetTheory(desc.ID, "irrelevant", fr)
-			if err != nil {
+			if err := c.compile(desc, dat); err != nil {
 				return err
 			}
-
 			c.RuleBases[desc.ID] = desc
+		}
+	}
+
+	return nil
+}
 
+// ruleBaseRevision returns the revision of a rulebase, the hash of its content
+func ruleBaseRevision(src []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(src))
+}
+
+// validRuleBaseID matches the ids of rulebases which can be used as file names
+var validRuleBaseID = regexp.MustCompile(`^[\w-][\w.-]*$`)
+
+// parseRuleBaseDescription parses the meta data of the YAML source of a rulebase
+func parseRuleBaseDescription(src []byte) (RuleBaseDescription, error) {
+	type rb struct {
+		Meta RuleBaseDescription
+	}
+	rby := rb{}
+	if err := yaml.Unmarshal(src, &rby); err != nil {
+		return RuleBaseDescription{}, structs.WrapErrWith(err, structs.NewHTTPError(err.Error(), http.StatusBadRequest))
+	}
+	desc := rby.Meta
+	if desc.ID == "" {
+		return RuleBaseDescription{}, structs.NewHTTPError("The rulebase has no meta id", http.StatusBadRequest)
+	}
+	desc.Revision = ruleBaseRevision(src)
+	desc.source = src
+	return desc, nil
+}
+
+// compile compiles the rulebase source into a theory, which is cached by the
+// checker with the revision of the description. The caller must hold the lock.
+func (c *ComplianceCheckerPlugin) compile(desc RuleBaseDescription, src []byte) error {
+	_, err := compileTheory(c.checker, desc, src)
+	return err
+}
+
+// compileTheory compiles the rulebase source into a theory, which is cached by
+// the checker with the revision of the description
+func compileTheory(checker *ComplianceChecker, desc RuleBaseDescription, src []byte) (theory *Theory, err error) {
+	// the yaml decoder of carneades panics on some malformed rulebases
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Could not compile the rulebase: %v", r)
+		}
+		if err != nil {
+			err = structs.WrapErrWith(err, structs.NewHTTPError(err.Error(), http.StatusBadRequest))
 		}
+	}()
+	return checker.GetTheory(desc.ID, desc.Revision, bytes.NewReader(src))
+}
+
+// AddRuleBase validates and compiles the YAML source of a new rulebase and saves it in
+// the RuleBaseDir, in a file named after the id of the rulebase. An error is returned if
+// the rulebase cannot be compiled or if there already is a rulebase with the same id.
+func (c *ComplianceCheckerPlugin) AddRuleBase(src []byte) (RuleBaseDescription, error) {
+	return c.saveRuleBase("", src)
+}
+
+// PutRuleBase validates and compiles the YAML source of the rulebase with the given id
+// and saves it, replacing the current version of the rulebase, if any. The id in the
+// meta data of the rulebase must be the given id. The current version is kept if the
+// rulebase cannot be compiled.
+func (c *ComplianceCheckerPlugin) PutRuleBase(ruleBaseID string, src []byte) (RuleBaseDescription, error) {
+	return c.saveRuleBase(ruleBaseID, src)
+}
+
+// saveRuleBase adds a new rulebase if ruleBaseID is empty, else it replaces the rulebase
+func (c *ComplianceCheckerPlugin) saveRuleBase(ruleBaseID string, src []byte) (RuleBaseDescription, error) {
+	desc, err := parseRuleBaseDescription(src)
+	if err != nil {
+		return desc, err
+	}
+	if ruleBaseID != "" && desc.ID != ruleBaseID {
+		return desc, structs.NewHTTPError(fmt.Sprintf("The id of the rulebase %s does not match %s", desc.ID, ruleBaseID), http.StatusBadRequest)
+	}
+	if !validRuleBaseID.MatchString(desc.ID) || IsRuleBaseTestFile(desc.ID+".yml") {
+		return desc, structs.NewHTTPError(fmt.Sprintf("Invalid rulebase id: %s", desc.ID), http.StatusBadRequest)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if old, ok := c.RuleBases[desc.ID]; ok {
+		if ruleBaseID == "" {
+			return desc, structs.NewHTTPError(fmt.Sprintf("Rulebase %s already exists", desc.ID), http.StatusConflict)
+		}
+		desc.Filename = old.Filename
+	} else {
+		desc.Filename = desc.ID + ".yml"
+		if _, err := os.Stat(filepath.Join(c.RuleBaseDir, desc.Filename)); err == nil {
+			return desc, structs.NewHTTPError(fmt.Sprintf("Rulebase file %s already exists", desc.Filename), http.StatusConflict)
+		}
+	}
+	if err := c.compile(desc, src); err != nil {
+		return desc, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(c.RuleBaseDir, desc.Filename), src, 0644); err != nil {
+		return desc, structs.WrapErrWith(err, structs.NewHTTPError(err.Error(), http.StatusInternalServerError))
 	}
+	c.RuleBases[desc.ID] = desc
+	return desc, nil
+}
 
+// DeleteRuleBase unloads the rulebase with the given id and deletes its file
+func (c *ComplianceCheckerPlugin) DeleteRuleBase(ruleBaseID string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	desc, ok := c.RuleBases[ruleBaseID]
+	if !ok {
+		return structs.NewHTTPError(fmt.Sprintf("Rulebase %s not found", ruleBaseID), http.StatusNotFound)
+	}
+	if err := os.Remove(filepath.Join(c.RuleBaseDir, desc.Filename)); err != nil && !os.IsNotExist(err) {
+		return structs.WrapErrWith(err, structs.NewHTTPError(err.Error(), http.StatusInternalServerError))
+	}
+	delete(c.RuleBases, ruleBaseID)
+	c.checker.Theories.Remove(ruleBaseID)
 	return nil
 }
 
+// RuleBaseDescriptions returns a copy of the descriptions of the loaded rulebases
+func (c *ComplianceCheckerPlugin) RuleBaseDescriptions() map[string]RuleBaseDescription {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	m := make(map[string]RuleBaseDescription, len(c.RuleBases))
+	for k, v := range c.RuleBases {
+		m[k] = v
+	}
+	return m
+}
+
+// SetSearchOptions sets the options bounding the search for compliant documents
+func (c *ComplianceCheckerPlugin) SetSearchOptions(opts SearchOptions) {
+	c.checker.Search = opts
+}
+
 //Shutdown does nothing yet
-func (c ComplianceCheckerPlugin) Shutdown() {
+func (c *ComplianceCheckerPlugin) Shutdown() {
 	// Nothing to do
 }
 
-// ruleBaseReader returns a reader for reading the JSON source file of the
-// rulebase with the given id
-func (c *ComplianceCheckerPlugin) ruleBaseReader(ruleBaseID string) io.Reader {
-	rb := c.RuleBases[ruleBaseID]
-	fr, err := os.Open(filepath.Join(c.RuleBaseDir, rb.Filename))
-	if err != nil {
-		fmt.Printf("Could not open the rulebase file: %s\n", rb.Filename)
-		return nil
+// theory returns the compiled theory of the rulebase with the given id, recompiling
+// the accepted source of the rulebase if the theory is no longer cached. The rulebase
+// file is not read again, since it may have been edited after the revision was accepted.
+func (c *ComplianceCheckerPlugin) theory(ruleBaseID string) (*Theory, error) {
+	c.lock.Lock()
+	rb, ok := c.RuleBases[ruleBaseID]
+	c.lock.Unlock()
+	if !ok {
+		return nil, structs.NewHTTPError(fmt.Sprintf("Rulebase %s not found", ruleBaseID), http.StatusNotFound)
 	}
-	return fr
+	return c.checker.GetTheory(ruleBaseID, rb.Revision, bytes.NewReader(rb.source))
 }
 
 // IsCompliant returns true iff the document complies with the rules in the given
 // rulebase.  An error is returned if document has syntax errors and cannot be parsed.
 func (c *ComplianceCheckerPlugin) IsCompliant(ruleBaseID string, document *NormalizedDocument) (bool, Explanation, error) {
-	r := c.ruleBaseReader(ruleBaseID)
-	theory, err := c.checker.GetTheory(ruleBaseID, "irrelevant", r)
+	theory, err := c.theory(ruleBaseID)
 	if err != nil {
 		return false, nil, err
 	}
 	return c.checker.IsCompliant(theory, document)
 }
 
+// Check returns the verdict of the rulebase with the given id for the document, which
+// is UNDECIDED if the rulebase does not decide whether the document requires consent,
+// along with the explanation and the unresolved issues causing an undecided verdict.
+func (c *ComplianceCheckerPlugin) Check(ruleBaseID string, document *NormalizedDocument) (ComplianceResult, error) {
+	theory, err := c.theory(ruleBaseID)
+	if err != nil {
+		return ComplianceResult{}, err
+	}
+	return c.checker.Check(theory, document)
+}
+
+// RuleBaseResult is the result of checking a document against one rulebase.
+// Compliant is true iff the verdict is COMPLIANT. Err is set if the document could not be checked.
+type RuleBaseResult struct {
+	Compliant   bool
+	Verdict     Verdict
+	Explanation Explanation
+	Issues      []UnresolvedIssue
+	Err         error
+}
+
+// IsCompliantAll checks the document against the rulebases with the given ids concurrently,
+// or against all loaded rulebases if no ids are given. The results are keyed by rulebase id.
+func (c *ComplianceCheckerPlugin) IsCompliantAll(ruleBaseIDs []string, document *NormalizedDocument) map[string]RuleBaseResult {
+	if len(ruleBaseIDs) == 0 {
+		for id := range c.RuleBaseDescriptions() {
+			ruleBaseIDs = append(ruleBaseIDs, id)
+		}
+	}
+	workers := c.checker.Search.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]RuleBaseResult, len(ruleBaseIDs))
+	for _, id := range ruleBaseIDs {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			r, err := c.Check(id, document)
+			lock.Lock()
+			results[id] = RuleBaseResult{r.Verdict == Compliant, r.Verdict, r.Explanation, r.Issues, err}
+			lock.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return results
+}
+
 // CompliantDocuments returns true iff the document complies with the rules in the given
 // rulebase.  An error is returned if document has syntax errors and cannot be parsed. If
 // the document is not compliant, false is returned along with a slice of compliant documents
@@ -142,10 +317,7 @@ func (c *ComplianceCheckerPlugin) IsCompliant(ruleBaseID string, document *Norma
 // for compliant documents is restarted each time CompliantDocuments is called, no matter
 // what the offset is.
 func (c *ComplianceCheckerPlugin) CompliantDocuments(ruleBaseID string, document *NormalizedDocument, maxResults int, offset int) (bool, []*NormalizedDocument, error) {
-	fmt.Println("Checking Compliance")
-	r := c.ruleBaseReader(ruleBaseID)
-
-	theory, err := c.checker.GetTheory(ruleBaseID, "irrelevant", r)
+	theory, err := c.theory(ruleBaseID)
 	if err != nil {
 		return false, nil, err
 	}
@@ -155,7 +327,6 @@ func (c *ComplianceCheckerPlugin) CompliantDocuments(ruleBaseID string, document
 	if err != nil {
 		return false, nil, err
 	}
-	fmt.Printf("Compliant: %t\n", compliant)
 	if compliant {
 		return true, nil, nil
 	}
@@ -174,14 +345,62 @@ func (c *ComplianceCheckerPlugin) CompliantDocuments(ruleBaseID string, document
 		temp, ok := <-docChan
 		if !ok {
 			// no further compliant documents available
-			fmt.Println("No more compliant documents available")
 			return false, docs, nil
 		}
-		fmt.Printf("Compliant Document Found: %+v \n", temp)
 		docs = append(docs, temp)
 	}
 
 	cncl.Cancel()
-	fmt.Println("Checking Cancelled")
 	return false, docs, nil
 }
+
+// ArgumentGraph returns the labelled argument graph constructed when checking
+// the compliance of the document with the rules in the given rulebase.
+func (c *ComplianceCheckerPlugin) ArgumentGraph(ruleBaseID string, document *NormalizedDocument) (*caes.ArgGraph, error) {
+	theory, err := c.theory(ruleBaseID)
+	if err != nil {
+		return nil, err
+	}
+	return c.checker.ArgumentGraph(theory, document)
+}
+
+// Query builds the argument graph of the document as IsCompliant does and returns the
+// statements matching the pattern, an atomic formula which may contain variables,
+// with their labels and the arguments pro and con.
+func (c *ComplianceCheckerPlugin) Query(ruleBaseID string, document *NormalizedDocument, pattern string) ([]QueryResult, error) {
+	p, err := ParseQuery(pattern)
+	if err != nil {
+		return nil, err
+	}
+	ag, err := c.ArgumentGraph(ruleBaseID, document)
+	if err != nil {
+		return nil, err
+	}
+	return Query(ag, p), nil
+}
+
+// CompliantAlternatives returns true iff the document complies with the rules in the given
+// rulebase. If the document is not compliant, false is returned along with a channel of
+// compliant documents based on the input document, which is closed when the search has
+// finished. Unlike CompliantDocuments, the search is not restarted for each page of results:
+// the caller must call cncl.Cancel() as soon as no further documents are needed.
+func (c *ComplianceCheckerPlugin) CompliantAlternatives(ruleBaseID string, document *NormalizedDocument, cncl Canceller) (bool, <-chan *NormalizedDocument, error) {
+	theory, err := c.theory(ruleBaseID)
+	if err != nil {
+		return false, nil, err
+	}
+	return c.checker.CompliantDocuments(theory, document, cncl)
+}
+
+// Repair returns true iff the document complies with the rules in the given rulebase.
+// If the document is not compliant, false is returned along with the cheapest
+// repair of the document found, using the default cost model. The codes of the
+// taxonomy are used to narrow qualifiers, shrink scopes and replace actions.
+// The repair is nil if no repair was found.
+func (c *ComplianceCheckerPlugin) Repair(ruleBaseID string, document *NormalizedDocument, tax structs.Taxonomy) (bool, *Repair, error) {
+	theory, err := c.theory(ruleBaseID)
+	if err != nil {
+		return false, nil, err
+	}
+	return c.checker.Repair(theory, document, tax, DefaultCostModel, MaxRepairEvaluations)
+}
@@ -0,0 +1,208 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+)
+
+// testRuleBaseWithID returns the test rulebase with the given meta id,
+// requiring consent for the action market iff market is true
+func testRuleBaseWithID(id string, market bool) []byte {
+	src := strings.Replace(testRuleBase, "id: test", "id: "+id, 1)
+	if !market {
+		src = strings.Replace(src, ",market,", ",marketing_disabled,", -1)
+	}
+	return []byte(src)
+}
+
+func testPlugin(t *testing.T) (*ComplianceCheckerPlugin, string) {
+	dir, err := ioutil.TempDir("", "duckRulebases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "test.yml"), testRuleBaseWithID("test", true), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := MakeComplianceCheckerPlugin(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Intialize(); err != nil {
+		t.Fatalf("Intialize() error = %v", err)
+	}
+	return c, dir
+}
+
+func httpStatus(err error) int {
+	if t, ok := err.(structs.HTTPError); ok {
+		return t.Status
+	}
+	return 0
+}
+
+func TestComplianceCheckerPlugin_saveRuleBase(t *testing.T) {
+	c, dir := testPlugin(t)
+	defer os.RemoveAll(dir)
+
+	if rb := c.RuleBaseDescriptions()["test"]; rb.Revision != ruleBaseRevision(testRuleBaseWithID("test", true)) || rb.Filename != "test.yml" {
+		t.Errorf("Intialize() description = %+v", rb)
+	}
+
+	tests := []struct {
+		name       string
+		id         string
+		src        []byte
+		wantStatus int
+	}{
+		{"add existing", "", testRuleBaseWithID("test", true), http.StatusConflict},
+		{"add", "", testRuleBaseWithID("other", true), 0},
+		{"add without id", "", []byte("meta:\n  title: no id\n"), http.StatusBadRequest},
+		{"add invalid id", "", testRuleBaseWithID("../x", true), http.StatusBadRequest},
+		{"add malformed", "", []byte("meta: [\n"), http.StatusBadRequest},
+		{"put mismatched id", "other", testRuleBaseWithID("test", true), http.StatusBadRequest},
+		{"put uncompilable", "other", []byte("meta:\n  id: other\nargument_schemes: 42\n"), http.StatusBadRequest},
+		{"put", "other", testRuleBaseWithID("other", false), 0},
+	}
+	for _, tt := range tests {
+		var err error
+		if tt.id == "" {
+			_, err = c.AddRuleBase(tt.src)
+		} else {
+			_, err = c.PutRuleBase(tt.id, tt.src)
+		}
+		if got := httpStatus(err); (err == nil) != (tt.wantStatus == 0) || got != tt.wantStatus {
+			t.Errorf("%q. saveRuleBase() error = %v, status %v, want status %v", tt.name, err, got, tt.wantStatus)
+		}
+	}
+
+	dat, err := ioutil.ReadFile(filepath.Join(dir, "other.yml"))
+	if err != nil || string(dat) != string(testRuleBaseWithID("other", false)) {
+		t.Errorf("saveRuleBase() did not save the rulebase: %v", err)
+	}
+	if rb := c.RuleBaseDescriptions()["other"]; rb.Revision != ruleBaseRevision(dat) {
+		t.Errorf("saveRuleBase() revision = %v, want %v", rb.Revision, ruleBaseRevision(dat))
+	}
+	if compliant, _, err := c.IsCompliant("other", actionDoc("market")); err != nil || !compliant {
+		t.Errorf("IsCompliant() = %v, %v after replacing the rulebase, want true", compliant, err)
+	}
+
+	if err := c.DeleteRuleBase("other"); err != nil {
+		t.Errorf("DeleteRuleBase() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other.yml")); !os.IsNotExist(err) {
+		t.Errorf("DeleteRuleBase() did not delete the file: %v", err)
+	}
+	if _, _, err := c.IsCompliant("other", actionDoc("market")); httpStatus(err) != http.StatusNotFound {
+		t.Errorf("IsCompliant() error = %v for a deleted rulebase, want status %v", err, http.StatusNotFound)
+	}
+	if err := c.DeleteRuleBase("other"); httpStatus(err) != http.StatusNotFound {
+		t.Errorf("DeleteRuleBase() error = %v, want status %v", err, http.StatusNotFound)
+	}
+}
+
+func TestComplianceCheckerPlugin_Reload(t *testing.T) {
+	c, dir := testPlugin(t)
+	defer os.RemoveAll(dir)
+
+	if compliant, _, err := c.IsCompliant("test", actionDoc("market")); err != nil || compliant {
+		t.Fatalf("IsCompliant() = %v, %v, want false", compliant, err)
+	}
+
+	// edit the rulebase, add a new one and a broken one
+	src := testRuleBaseWithID("test", false)
+	if err := ioutil.WriteFile(filepath.Join(dir, "test.yml"), src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "new.yml"), testRuleBaseWithID("new", true), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "broken.yml"), []byte("meta: ["), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c.Reload()
+
+	rbs := c.RuleBaseDescriptions()
+	if rbs["test"].Revision != ruleBaseRevision(src) {
+		t.Errorf("Reload() revision = %v, want %v", rbs["test"].Revision, ruleBaseRevision(src))
+	}
+	if _, ok := rbs["new"]; !ok || len(rbs) != 2 {
+		t.Errorf("Reload() rulebases = %v, want test and new", rbs)
+	}
+	if compliant, _, err := c.IsCompliant("test", actionDoc("market")); err != nil || !compliant {
+		t.Errorf("IsCompliant() = %v, %v after reloading the rulebase, want true", compliant, err)
+	}
+
+	// delete the new rulebase
+	if err := os.Remove(filepath.Join(dir, "new.yml")); err != nil {
+		t.Fatal(err)
+	}
+	c.Reload()
+	if _, ok := c.RuleBaseDescriptions()["new"]; ok {
+		t.Errorf("Reload() did not unload the deleted rulebase")
+	}
+}
+
+func TestComplianceCheckerPlugin_theory_acceptedSource(t *testing.T) {
+	c, dir := testPlugin(t)
+	defer os.RemoveAll(dir)
+
+	// edits of the file which were not reloaded, or were rejected, do not change the
+	// rulebase when its theory is no longer cached
+	for _, src := range [][]byte{testRuleBaseWithID("test", false), []byte("meta: [")} {
+		if err := ioutil.WriteFile(filepath.Join(dir, "test.yml"), src, 0644); err != nil {
+			t.Fatal(err)
+		}
+		c.checker.Theories.Remove("test")
+		if compliant, _, err := c.IsCompliant("test", actionDoc("market")); err != nil || compliant {
+			t.Errorf("IsCompliant() = %v, %v with the file %q, want false of the accepted revision", compliant, err, src)
+		}
+	}
+	c.Reload()
+	if compliant, _, err := c.IsCompliant("test", actionDoc("market")); err != nil || compliant {
+		t.Errorf("IsCompliant() = %v, %v after rejecting the file, want false of the accepted revision", compliant, err)
+	}
+}
+
+func TestComplianceCheckerPlugin_IsCompliantAll(t *testing.T) {
+	c, dir := testPlugin(t)
+	defer os.RemoveAll(dir)
+	if _, err := c.AddRuleBase(testRuleBaseWithID("other", false)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		ruleBaseIDs   []string
+		wantCompliant map[string]bool
+		wantStatus    map[string]int
+	}{
+		{"all", nil, map[string]bool{"test": false, "other": true}, nil},
+		{"subset", []string{"other"}, map[string]bool{"other": true}, nil},
+		{"unknown", []string{"test", "unknown"}, map[string]bool{"test": false}, map[string]int{"unknown": http.StatusNotFound}},
+	}
+	for _, tt := range tests {
+		got := c.IsCompliantAll(tt.ruleBaseIDs, actionDoc("market"))
+		if len(got) != len(tt.wantCompliant)+len(tt.wantStatus) {
+			t.Errorf("%q. IsCompliantAll() = %v, want %d results", tt.name, got, len(tt.wantCompliant)+len(tt.wantStatus))
+		}
+		for id, want := range tt.wantCompliant {
+			if r := got[id]; r.Err != nil || r.Compliant != want || r.Explanation == nil {
+				t.Errorf("%q. IsCompliantAll()[%s] = %v, %v, want %v", tt.name, id, r.Compliant, r.Err, want)
+			}
+		}
+		for id, want := range tt.wantStatus {
+			if status := httpStatus(got[id].Err); status != want {
+				t.Errorf("%q. IsCompliantAll()[%s] error = %v, want status %v", tt.name, id, got[id].Err, want)
+			}
+		}
+	}
+}
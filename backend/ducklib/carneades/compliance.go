@@ -5,15 +5,16 @@
 package carneades
 
 import (
-	"errors"
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"runtime"
+	"time"
 
 	"github.com/carneades/carneades-4/src/engine/caes"
-	"github.com/carneades/carneades-4/src/engine/caes/encoding/graphml"
 	y "github.com/carneades/carneades-4/src/engine/caes/encoding/yaml"
 )
 
@@ -32,91 +33,167 @@ func (c Canceller) Cancel() {
 
 type VersionedTheory struct {
 	revision string
-	theory   *caes.Theory
+	theory   *Theory
+}
+
+// Theory is a theory compiled from a rulebase, together with the explanation
+// schema of the rulebase, so that every check with the theory explains the
+// statements with the same predicates, whether the theory is cached or not
+type Theory struct {
+	*caes.Theory
+	Schema ExplanationSchema
+}
+
+// SearchOptions bound the search for compliant documents of CompliantDocuments
+type SearchOptions struct {
+	Workers        int           // number of compliance checks run in parallel
+	MaxEvaluations int           // maximum number of compliance checks, 0 for no limit
+	Timeout        time.Duration // maximum duration of the search, 0 for no limit
+}
+
+// DefaultSearchOptions are the SearchOptions of a new ComplianceChecker
+var DefaultSearchOptions = SearchOptions{
+	Workers:        runtime.NumCPU(),
+	MaxEvaluations: 1000,
+	Timeout:        30 * time.Second,
 }
 
 type ComplianceChecker struct {
-	Theories map[string]VersionedTheory
+	Theories   *TheoryCache
+	Search     SearchOptions
+	ruleStores *ruleStores // CHR rule stores of the cached theories
 }
 
 func MakeComplianceChecker() *ComplianceChecker {
-	return &ComplianceChecker{make(map[string]VersionedTheory)}
+	stores := newRuleStores()
+	theories := NewTheoryCache(DefaultTheoryCacheSize)
+	theories.evicted = func(theory *Theory) {
+		stores.remove(theory.Theory)
+	}
+	return &ComplianceChecker{Theories: theories, Search: DefaultSearchOptions, ruleStores: stores}
 }
 
 // GetTheory retrieves the theory for the given ruleBaseID. If no version of the
 // rulebase has been compiled or its revision is not equal to the revision
 // used to compile the theory,
 // the theory is first updated, by reading the JSON source from rbSrc,
-// and compiling the rulebase into a theory and updating the Theories of the
-// ComplianceChecker.
+// and compiling the rulebase into a theory, with the explanation schema of the
+// rulebase, and updating the Theories of the
+// ComplianceChecker. GetTheory may be called concurrently: a revision being
+// compiled is compiled only once, and rbSrc is not read if the theory is cached.
 // If there are no errors, the returned error will be nil.
-func (c ComplianceChecker) GetTheory(ruleBaseID string, revision string, rbSrc io.Reader) (*caes.Theory, error) {
-
-	vt, found := c.Theories[ruleBaseID]
-	if !found || revision != vt.revision {
-
-		// Compile the rulebase, update the theory cache and return the
-		// theory.  Or return an error if the rulebase cannot be compiled.
-		ag, err := y.Import(rbSrc)
+func (c ComplianceChecker) GetTheory(ruleBaseID string, revision string, rbSrc io.Reader) (*Theory, error) {
+	return c.Theories.Get(ruleBaseID, revision, func() (*Theory, error) {
+		// Compile the rulebase and return the theory, which is cached by
+		// Get. Or return an error if the rulebase cannot be compiled.
+		src, err := ioutil.ReadAll(rbSrc)
+		if err != nil {
+			return nil, err
+		}
+		ag, err := importRuleBase(bytes.NewReader(src))
 		if err != nil {
 			fmt.Printf("Could not parse the rulebase\n")
 			return nil, err
 		}
+		schema, err := parseExplanationSchema(src, ag.Theory)
+		if err != nil {
+			return nil, err
+		}
 		log.Printf("rulebase successfully imported, with %d schemes and %d predicates\n", len(ag.Theory.ArgSchemes), len(ag.Theory.Language))
 		log.Printf("title: %s\n", ag.Metadata["title"].(string))
-		c.Theories[ruleBaseID] = VersionedTheory{revision, ag.Theory}
-		return ag.Theory, nil
-	}
-	fmt.Printf("rulebase successfully imported\n")
-	return vt.theory, nil
+		return &Theory{ag.Theory, schema}, nil
+	})
+}
+
+// importRuleBase imports the YAML source of a rulebase into an argument graph with
+// the theory of the rulebase
+func importRuleBase(rbSrc io.Reader) (*caes.ArgGraph, error) {
+	encoderLock.Lock()
+	defer encoderLock.Unlock()
+	return y.Import(rbSrc)
 }
 
 /*
-	IsCompliant does the following:
+	Check does the following:
 		* Translates the data use statements in the document into Carneades assumptions (terms)
 		* Applies the theory to the assumptions, using the Carneades inference engine,
 		    to construct a Carneades argument graph
 	    * Evaluates the argument graph to label the statements in the graph in, out or undecided.
-		* Returns true if and only if the statement in the argument representing
-		    the proposition that the document does not require consent is in.
-	Whether or not the document is compliant, an explanation is returned.
+		* Returns the verdict COMPLIANT, NON_COMPLIANT or UNDECIDED if the statement in the
+		    argument graph representing the proposition that the document does not require
+		    consent is in, out or undecided.
+	Whatever the verdict is, an explanation is returned.
 	If the document is not compliant, i.e. requires consent, the explanation
 	lists the statements which require consent.
 	If the document is compliant, i.e. does not require consent, the explanation
 	lists assumptions which were made to reach this conclusion, i.e. whether it
 	was assumed that a statement is not about pii and whether it was assumed
 	that there is a legitimate interest in the content information.
+	If the verdict is undecided, the result lists the unresolved issues of the
+	argument graph which caused it, so that the rulebase can be fixed.
 	The error returned will be nil if and only if no errors occur this process.
 */
+func (c ComplianceChecker) Check(theory *Theory, document *NormalizedDocument) (ComplianceResult, error) {
+	ag, err := c.ArgumentGraph(theory, document)
+	if err != nil {
+		return ComplianceResult{}, err
+	}
+
+	// write the argument graph in graphml to a temporary file
+	// so that it can be visualized for debugging purposes
+
+	if DEBUG {
+		f, err := ioutil.TempFile(os.TempDir(), "duckGraphml")
+		if err == nil {
+			ExportGraph(f, ag, GraphML)
+			f.Close()
+		}
+	}
+
+	v, issues, err := verdict(ag)
+	if err != nil {
+		return ComplianceResult{}, err
+	}
+	e, err := c.GetExplanation(theory, ag)
+	if err != nil {
+		return ComplianceResult{}, err
+	}
+
+	return ComplianceResult{Verdict: v, Explanation: e, Issues: issues}, nil
+}
+
+// IsCompliant returns true if and only if the verdict of Check is COMPLIANT, i.e. an
+// undecided document is not compliant, along with the explanation.
+func (c ComplianceChecker) IsCompliant(theory *Theory, document *NormalizedDocument) (bool, Explanation, error) {
+	r, err := c.Check(theory, document)
+	if err != nil {
+		return false, nil, err
+	}
+	return r.Verdict == Compliant, r.Explanation, nil
+}
 
-func (c ComplianceChecker) IsCompliant(theory *caes.Theory, document *NormalizedDocument) (bool, Explanation, error) {
-	// func (c ComplianceChecker) IsCompliant(theory *caes.Theory, document *NormalizedDocument) (bool, error) {
+// ArgumentGraph constructs the argument graph for the document, by assuming
+// its data use statements, is a relationships and the facts of its assumption sets
+// and applying the theory to these assumptions, and labels the statements of the
+// graph in, out or undecided.
+func (c ComplianceChecker) ArgumentGraph(theory *Theory, document *NormalizedDocument) (*caes.ArgGraph, error) {
+	ag := assumeDocument(theory, document)
+	if err := c.inferAndLabel(ag); err != nil {
+		return nil, err
+	}
+	return ag, nil
+}
+
+// assumeDocument constructs an argument graph with the theory, whose assumptions are the
+// data use statements, is a relationships, facts and facts of the assumption sets of the document
+func assumeDocument(theory *Theory, document *NormalizedDocument) *caes.ArgGraph {
 	// Construct the argument graph
 	ag := caes.NewArgGraph()
-	ag.Theory = theory
+	ag.Theory = theory.Theory
 	// add statements for the data use statements in the document
 	// to the argument graph, and assume them to be true.
 	for _, s := range document.Statements {
-		var passive bool
-		if s.Passive {
-			passive = true
-		} else {
-			passive = false
-		}
-
-		stmtID := fmt.Sprintf("dataUseStatement(dus(%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%d,%t))",
-			s.UseScopeCode,
-			s.UseScopeLocation,
-			s.QualifierCode,
-			s.DataCategoryCode,
-			s.SourceScopeCode,
-			s.SourceScopeLocation,
-			s.ActionCode,
-			s.ResultScopeCode,
-			s.ResultScopeLocation,
-			s.TrackingID,
-			s.PlaceInStruct,
-			passive)
+		stmtID := dusTerm(s).String()
 
 		stmt := &caes.Statement{
 			Id:       stmtID,
@@ -131,7 +208,7 @@ func (c ComplianceChecker) IsCompliant(theory *caes.Theory, document *Normalized
 	// to the argument graph, and assume them to be true.
 
 	for k, v := range document.IsA {
-		isa := fmt.Sprintf("isA(%s,%s)", k, v)
+		isa := isATerm(k, v).String()
 		if DEBUG {
 			log.Println(isa)
 		}
@@ -147,38 +224,39 @@ func (c ComplianceChecker) IsCompliant(theory *caes.Theory, document *Normalized
 	if DEBUG && (document.IsA == nil || len(document.IsA) == 0) {
 		log.Println("IsA is empty.")
 	}
-	// derive arguments by applying the theory of the argument graph to
-	// its assumptions
-	err := ag.Infer()
-	if err != nil {
-		return false, nil, err
-	}
-
-	// evaluate the argument graph
-	l := ag.GroundedLabelling()
-	ag.ApplyLabelling(l)
-
-	// write the argument graph in dot to a temporary file
-	// so that it can be visualized for debugging purposes
-
-	if DEBUG {
-		f, err := ioutil.TempFile(os.TempDir(), "duckGraphml")
-		if err == nil {
-			graphml.Export(f, ag)
+	// add the facts of the document, e.g. those derived from the taxonomy
+	for _, f := range document.Facts {
+		fact, err := ParseFact(f)
+		if err != nil {
+			log.Printf("Ignoring the fact %s of document %s: %s", f, document.ID, err)
+			continue
 		}
+		if _, ok := ag.Statements[fact]; ok {
+			continue
+		}
+		ag.Statements[fact] = &caes.Statement{
+			Id:       fact,
+			Metadata: make(map[string]interface{}),
+			Text:     fact,
+			Args:     []*caes.Argument{}}
+		ag.Assumptions = append(ag.Assumptions, fact)
 	}
+	// add the facts of the assumption sets of the document
+	assume(ag, document.AssumptionSets)
+	return ag
+}
 
-	// return true iff the notDocConsentRequired statement is in
-	s, ok := ag.Statements["notDocConsentRequired"]
-	if !ok {
-		return false, nil, errors.New("notDocConsentRequired is not a statement in the argument graph")
-	}
-	e, err := c.GetExplanation(theory, ag)
-	if err != nil {
-		return false, nil, err
+// inferAndLabel derives arguments by applying the theory of the argument graph to its
+// assumptions and the arguments already in the graph, and labels the statements
+func (c ComplianceChecker) inferAndLabel(ag *caes.ArgGraph) error {
+	if err := c.infer(ag); err != nil {
+		return err
 	}
 
-	return s.Label == caes.In, e, nil
+	// evaluate the argument graph
+	l := ag.GroundedLabelling()
+	ag.ApplyLabelling(l)
+	return nil
 }
 
 func removeStatement(d *NormalizedDocument, i int) (*NormalizedDocument, error) {
@@ -208,7 +286,10 @@ func removeStatement(d *NormalizedDocument, i int) (*NormalizedDocument, error)
 	  and the channel returned will be closed. If the input document is not
 	  compliant, the bool result will be false, and compliant alternative documents based in
 	  input document will returned in the channel. The documents returend will have
-	  minimal changes sufficient to achieve compliance. The input document is not modified.
+	  minimal changes sufficient to achieve compliance, i.e. no document returned contains
+	  all the statements of a document returned before it. The input document is not modified.
+	* The search is breadth-first, by the number of statements removed, and bounded by the
+	  Search options of the ComplianceChecker.
 	  The coroutine closes the channel when it has finished the search for compliant documents.
 An error will be returned only if was not possible to check the compliance of the input document,
 before starting the coroutine to search for compliant alternatives.
@@ -216,7 +297,7 @@ The caller must bind c to a newly constructed Canceller, with MakeCanceller().
 If no error is returned (i.e. error is nil) the caller should call c.Cancel() when no further
 documents are needed, to cause the coroutine to be terminated.
 */
-func (c ComplianceChecker) CompliantDocuments(theory *caes.Theory, doc *NormalizedDocument, cncl Canceller) (bool, <-chan *NormalizedDocument, error) {
+func (c ComplianceChecker) CompliantDocuments(theory *Theory, doc *NormalizedDocument, cncl Canceller) (bool, <-chan *NormalizedDocument, error) {
 	compliant, _, err := c.IsCompliant(theory, doc)
 	if err != nil {
 		return false, nil, err
@@ -226,66 +307,12 @@ func (c ComplianceChecker) CompliantDocuments(theory *caes.Theory, doc *Normaliz
 		return true, nil, nil
 	}
 
-	// Traverse the space of documents, breadth-first, with subsets of the data use
-	// statements of doc, and push each alternative documents down the
-	// variants channel.
-
-	variants := make(chan *NormalizedDocument)
-
-	// generate documents with subsets of the data use documents
-	// and push them down the varients channel
-	var subsets func(int, *NormalizedDocument)
-	subsets = func(i int, d *NormalizedDocument) {
-		if i < 0 {
-			return
-		}
-		select {
-		case <-cncl:
-			return // cancelled
-		default: // continue
-		}
-		// ToDo: check that the statement order below
-		// performs breadth-first search, not depth-first
-		d2, err := removeStatement(d, i)
-		if err != nil {
-			return
-		} // indexing error should not happen
-		fmt.Printf(".") // debugging
-		variants <- d2
-		subsets(i-1, d)
-		subsets(i-1, d2)
-	}
-	go func() {
-		subsets(len(doc.Statements)-1, doc)
-		close(variants)
-	}()
-
-	// Filter out the noncompliant documents
+	// Search the space of documents with subsets of the data use statements
+	// of doc and push each compliant document down the compliantVariants channel.
 	compliantVariants := make(chan *NormalizedDocument)
-
 	go func() {
-		for {
-			select {
-			case <-cncl:
-				return // cancelled
-			case d3, ok := <-variants:
-				if !ok { // no more variants available
-					close(compliantVariants)
-					return
-				}
-
-				compliant, _, err := c.IsCompliant(theory, d3)
-				if err != nil {
-					fmt.Printf("Compliance checking error: %v\n", err)
-				} else if compliant {
-					compliantVariants <- d3
-					fmt.Printf("+%d", len(d3.Statements))
-				} else {
-					fmt.Printf("-%d", len(d3.Statements))
-				}
-			default: // do nothing, to avoid blocking
-			}
-		}
+		c.searchSubsets(theory, doc, cncl, compliantVariants)
+		close(compliantVariants)
 	}()
 
 	return false, compliantVariants, nil
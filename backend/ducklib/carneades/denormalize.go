@@ -0,0 +1,288 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+)
+
+// DroppedClause is a clause of a statement of the original document whose data categories
+// are not all in the denormalized document. Clause is 0 for the data category of the
+// statement and i for the i-th of its DataCategories. Categories are the dropped data
+// categories: the one of the clause if it was dropped entirely, otherwise the dropped
+// subcategories the normalizer unfolded it into.
+type DroppedClause struct {
+	TrackingID       string   `json:"trackingId"`
+	Clause           int      `json:"clause"`
+	QualifierCode    string   `json:"qualifierCode"`
+	DataCategoryCode string   `json:"dataCategoryCode"`
+	Categories       []string `json:"categories"`
+}
+
+// subStatement is a statement of a normalized document derived from the statement of the
+// original document at index orig, as the k-th sub-statement unfolded from its clauses or,
+// if k is -1, as the statement itself
+type subStatement struct {
+	NormalizedStatement
+	orig, k int
+}
+
+// groupKey are the fields of sub-statements which have to be equal to regroup them
+type groupKey struct {
+	useScope, sourceScope, resultScope, action, tag string
+	passive                                         bool
+}
+
+// Denormalize rebuilds the document of the author from a normalized document derived from
+// it, e.g. an alternative or a repair. The sub-statements the normalizer unfolded from the
+// and/except clauses of a statement are regrouped into one statement, with the clauses of
+// the author as far as all of their sub-statements are kept. Sub-statements which differ in
+// other fields than the data category and qualifier, e.g. a shrunk scope, form statements of
+// their own with the tracking id of their first sub-statement. Scopes and qualifiers the
+// author omitted are omitted again while the defaults are unchanged. Statements which are
+// not derived from the original document are added as they are.
+func (n *Normalizer) Denormalize(doc *NormalizedDocument) (*structs.Document, []DroppedClause) {
+	// the unfolded categories of the statements with clauses, by their index
+	expansions := make([][]clause, len(n.original.Statements))
+	origins := make(map[string]subStatement)
+	for i, stmt := range n.original.Statements {
+		if len(stmt.DataCategories) == 0 {
+			origins[stmt.TrackingID] = subStatement{orig: i, k: -1}
+			continue
+		}
+		expansions[i] = n.expandCategories(fillDefaults(stmt))
+		for k := range expansions[i] {
+			origins[fmt.Sprintf("%s-%d", stmt.TrackingID, k)] = subStatement{orig: i, k: k}
+		}
+	}
+
+	subs := make([][]subStatement, len(n.original.Statements))
+	added := []structs.Statement{}
+	for _, s := range doc.Statements {
+		o, ok := origins[s.TrackingID]
+		if !ok {
+			added = append(added, s.Statement)
+			continue
+		}
+		subs[o.orig] = append(subs[o.orig], subStatement{s, o.orig, o.k})
+	}
+
+	denormalized := n.original
+	denormalized.Statements = []structs.Statement{}
+	dropped := []DroppedClause{}
+	for i, orig := range n.original.Statements {
+		if expansions[i] == nil {
+			if len(subs[i]) == 0 {
+				dropped = append(dropped, DroppedClause{orig.TrackingID, 0, orig.QualifierCode, orig.DataCategoryCode, []string{orig.DataCategoryCode}})
+			}
+			for _, s := range subs[i] {
+				stmt := restoreDefaults(orig, s.Statement)
+				stmt.DataCategories = orig.DataCategories
+				denormalized.Statements = append(denormalized.Statements, stmt)
+			}
+			continue
+		}
+		for g, group := range groupSubStatements(subs[i]) {
+			stmt := n.regroup(orig, expansions[i], group)
+			if g > 0 {
+				stmt.TrackingID = group[0].TrackingID
+			}
+			denormalized.Statements = append(denormalized.Statements, stmt)
+		}
+		dropped = append(dropped, droppedClauses(orig, expansions[i], subs[i])...)
+	}
+	denormalized.Statements = append(denormalized.Statements, added...)
+	return &denormalized, dropped
+}
+
+// groupSubStatements partitions sub-statements by their fields other than the data category
+// and qualifier, in the order of their first sub-statements
+func groupSubStatements(subs []subStatement) [][]subStatement {
+	groups := [][]subStatement{}
+	index := make(map[groupKey]int)
+	for _, s := range subs {
+		key := groupKey{s.UseScopeCode, s.SourceScopeCode, s.ResultScopeCode, s.ActionCode, "", s.Passive}
+		if s.Tag != nil {
+			key.tag = *s.Tag
+		}
+		g, ok := index[key]
+		if !ok {
+			g = len(groups)
+			index[key] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], s)
+	}
+	return groups
+}
+
+// regroup returns the statement of the original statement orig with and/except clauses
+// made of a group of its sub-statements. A clause of orig whose sub-statements are all in
+// the group, with the same qualifier, is restored, followed by the except clauses of orig
+// if they leave out categories of a restored clause. The other sub-statements become and
+// clauses of their own data categories, which the except clauses do not affect.
+func (n *Normalizer) regroup(orig structs.Statement, expansion []clause, group []subStatement) structs.Statement {
+	included, excluded := splitClauses(fillDefaults(orig))
+
+	present := make(map[structs.DataCategories]bool)
+	qualifiers := make(map[int]string) // the qualifier of the first sub-statement of a clause
+	for _, s := range group {
+		present[structs.DataCategories{Op: structs.AND, QualifierCode: s.QualifierCode, DataCategoryCode: s.DataCategoryCode}] = true
+		if _, ok := qualifiers[expansion[s.k].Index]; !ok {
+			qualifiers[expansion[s.k].Index] = s.QualifierCode
+		}
+	}
+
+	// the restored clauses and the sub-statements they cover
+	restored := make(map[int]structs.DataCategories)
+	covered := make(map[structs.DataCategories]bool)
+	excepted := false // whether the except clauses leave out categories of a restored clause
+	for _, inc := range included {
+		q, ok := qualifiers[inc.Index]
+		if !ok {
+			continue
+		}
+		codes := n.excludeCategories(inc.DataCategoryCode, excluded)
+		whole := true
+		for _, code := range codes {
+			if !present[structs.DataCategories{Op: structs.AND, QualifierCode: q, DataCategoryCode: code}] {
+				whole = false
+				break
+			}
+		}
+		if !whole {
+			continue
+		}
+		for _, code := range codes {
+			covered[structs.DataCategories{Op: structs.AND, QualifierCode: q, DataCategoryCode: code}] = true
+		}
+		if !reflect.DeepEqual(codes, []string{inc.DataCategoryCode}) {
+			excepted = true
+		}
+		restored[inc.Index] = structs.DataCategories{Op: structs.AND, QualifierCode: restoreQualifier(authorClause(orig, inc.Index).QualifierCode, q), DataCategoryCode: inc.DataCategoryCode}
+	}
+
+	clauses := []structs.DataCategories{}
+	emitted := make(map[int]bool)
+	for _, s := range group {
+		c := expansion[s.k].Index
+		if dcat, ok := restored[c]; ok {
+			if !emitted[c] {
+				emitted[c] = true
+				clauses = append(clauses, dcat)
+			}
+			continue
+		}
+		dcat := structs.DataCategories{Op: structs.AND, QualifierCode: s.QualifierCode, DataCategoryCode: s.DataCategoryCode}
+		if !covered[dcat] {
+			covered[dcat] = true
+			dcat.QualifierCode = restoreQualifier(authorClause(orig, c).QualifierCode, s.QualifierCode)
+			clauses = append(clauses, dcat)
+		}
+	}
+
+	stmt := restoreDefaults(orig, group[0].Statement)
+	stmt.TrackingID = orig.TrackingID
+	stmt.QualifierCode = clauses[0].QualifierCode
+	stmt.DataCategoryCode = clauses[0].DataCategoryCode
+	stmt.DataCategories = append([]structs.DataCategories{}, clauses[1:]...)
+	// the except clauses only apply to the restored clauses
+	if excepted {
+		for i, dcat := range orig.DataCategories {
+			if dcat.Op == structs.EXCEPT {
+				stmt.DataCategories = append(stmt.DataCategories, orig.DataCategories[i:]...)
+				break
+			}
+		}
+	}
+	return stmt
+}
+
+// droppedClauses returns the clauses of the original statement orig with sub-statements
+// which are not among its sub-statements subs
+func droppedClauses(orig structs.Statement, expansion []clause, subs []subStatement) []DroppedClause {
+	kept := make(map[int]bool)
+	for _, s := range subs {
+		kept[s.k] = true
+	}
+	included, _ := splitClauses(orig)
+	dropped := []DroppedClause{}
+	for _, inc := range included {
+		all, missing := 0, []string{}
+		for k, c := range expansion {
+			if c.Index != inc.Index {
+				continue
+			}
+			all++
+			if !kept[k] {
+				missing = append(missing, c.DataCategoryCode)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		if len(missing) == all {
+			missing = []string{inc.DataCategoryCode}
+		}
+		author := authorClause(orig, inc.Index)
+		dropped = append(dropped, DroppedClause{orig.TrackingID, inc.Index, author.QualifierCode, author.DataCategoryCode, missing})
+	}
+	return dropped
+}
+
+// authorClause returns the clause of a statement as the author wrote it,
+// 0 for the data category of the statement and i for the i-th of its DataCategories
+func authorClause(stmt structs.Statement, i int) structs.DataCategories {
+	if i == 0 {
+		return structs.DataCategories{Op: structs.AND, QualifierCode: stmt.QualifierCode, DataCategoryCode: stmt.DataCategoryCode}
+	}
+	return stmt.DataCategories[i-1]
+}
+
+// restoreQualifier returns the qualifier omitted by the author if the qualifier is the default
+func restoreQualifier(author, qualifier string) string {
+	if author == "" && qualifier == "unqualified" {
+		return ""
+	}
+	return qualifier
+}
+
+// restoreDefaults returns the statement s derived from the statement orig of the author
+// with the qualifier and as many of the scopes the author omitted omitted again as
+// filling them in again yields the scopes of s
+func restoreDefaults(orig structs.Statement, s structs.Statement) structs.Statement {
+	s.QualifierCode = restoreQualifier(orig.QualifierCode, s.QualifierCode)
+
+	scopes := []*string{&s.UseScopeCode, &s.SourceScopeCode, &s.ResultScopeCode}
+	omittable := []bool{orig.UseScopeCode == "", orig.SourceScopeCode == "", orig.ResultScopeCode == ""}
+	best, bestOmitted := s, 0
+	// the subsets of the scopes, as bits of use, source and result scope
+	for subset := 1; subset < 1<<uint(len(scopes)); subset++ {
+		omitted, count := s, 0
+		omittedScopes := []*string{&omitted.UseScopeCode, &omitted.SourceScopeCode, &omitted.ResultScopeCode}
+		for i := range scopes {
+			if subset&(1<<uint(i)) == 0 {
+				continue
+			}
+			if !omittable[i] {
+				count = -1
+				break
+			}
+			*omittedScopes[i] = ""
+			count++
+		}
+		if count <= bestOmitted {
+			continue
+		}
+		filled := fillDefaults(omitted)
+		if filled.UseScopeCode == s.UseScopeCode && filled.SourceScopeCode == s.SourceScopeCode && filled.ResultScopeCode == s.ResultScopeCode {
+			best, bestOmitted = omitted, count
+		}
+	}
+	return best
+}
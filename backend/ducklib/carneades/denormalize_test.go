@@ -0,0 +1,150 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+	"github.com/Microsoft/DUCK/backend/ducklib/taxonomy"
+)
+
+func testNormalizer(t *testing.T, stmts ...structs.Statement) (*Normalizer, *NormalizedDocument) {
+	tax, err := taxonomy.Load(filepath.Join("..", "..", "..", "frontend", "src", "assets", "config"), taxonomy.DefaultLocale)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	doc := structs.Document{
+		Statements: stmts,
+		Dictionary: structs.Dictionary{"cards": {Code: "cards", Category: "4.2"}},
+	}
+	n, _ := NewNormalizerWithDictionary(doc, nil, tax)
+	normDoc, err := n.GetNormalized()
+	if err != nil {
+		t.Fatalf("GetNormalized() error = %v", err)
+	}
+	return n, normDoc
+}
+
+func dataCategories(clauses ...structs.DataCategories) []structs.DataCategories {
+	return append([]structs.DataCategories{}, clauses...)
+}
+
+func TestNormalizer_Denormalize_roundTrip(t *testing.T) {
+	and := structs.DataCategories{Op: structs.AND, QualifierCode: "identified_data", DataCategoryCode: "account_data"}
+	tests := []struct {
+		name string
+		stmt structs.Statement
+	}{
+		{"omitted scopes and qualifier", structs.Statement{
+			SourceScopeCode: "capability", ActionCode: "provide", DataCategoryCode: "customer_content", TrackingID: "s0"}},
+		{"and", structs.Statement{
+			UseScopeCode: "capability", ActionCode: "provide", DataCategoryCode: "customer_content", TrackingID: "s0",
+			DataCategories: dataCategories(and)}},
+		{"except", structs.Statement{
+			UseScopeCode: "capability", ActionCode: "provide", DataCategoryCode: "derived_data", TrackingID: "s0",
+			DataCategories: dataCategories(and, structs.DataCategories{Op: structs.EXCEPT, DataCategoryCode: "derived_data_user_location"})}},
+		{"except a custom code", structs.Statement{
+			UseScopeCode: "capability", ActionCode: "provide", DataCategoryCode: "account_data", TrackingID: "s0",
+			DataCategories: dataCategories(structs.DataCategories{Op: structs.EXCEPT, DataCategoryCode: "cards"})}},
+	}
+	for _, tt := range tests {
+		n, normDoc := testNormalizer(t, tt.stmt)
+		got, dropped := n.Denormalize(normDoc)
+		if !reflect.DeepEqual(got.Statements, []structs.Statement{tt.stmt}) {
+			t.Errorf("%q. Denormalize() statements = %+v, want %+v", tt.name, got.Statements, []structs.Statement{tt.stmt})
+		}
+		if len(dropped) != 0 {
+			t.Errorf("%q. Denormalize() dropped = %+v, want none", tt.name, dropped)
+		}
+	}
+}
+
+func TestNormalizer_Denormalize(t *testing.T) {
+	except := structs.Statement{
+		UseScopeCode: "capability", ActionCode: "provide", DataCategoryCode: "customer_content", TrackingID: "s0",
+		DataCategories: dataCategories(
+			structs.DataCategories{Op: structs.AND, QualifierCode: "identified_data", DataCategoryCode: "account_data"},
+			structs.DataCategories{Op: structs.EXCEPT, DataCategoryCode: "customer_content_health"}),
+	}
+	plain := structs.Statement{UseScopeCode: "capability", ActionCode: "provide", DataCategoryCode: "provider_data", TrackingID: "s1"}
+	tests := []struct {
+		name    string
+		edit    func(d *NormalizedDocument) // edits the normalized document of except and plain
+		want    []structs.Statement
+		dropped []DroppedClause
+	}{
+		{"subcategory dropped", func(d *NormalizedDocument) {
+			// customer_content_genetic
+			d2, _ := removeStatement(d, 2)
+			*d = *d2
+		}, []structs.Statement{{
+			UseScopeCode: "capability", ActionCode: "provide", DataCategoryCode: "customer_content_credentials", TrackingID: "s0",
+			DataCategories: dataCategories(
+				structs.DataCategories{Op: structs.AND, DataCategoryCode: "customer_content_contact"},
+				structs.DataCategories{Op: structs.AND, DataCategoryCode: "customer_content_biometric"},
+				structs.DataCategories{Op: structs.AND, DataCategoryCode: "customer_content_children"},
+				structs.DataCategories{Op: structs.AND, DataCategoryCode: "customer_content_opinions"},
+				structs.DataCategories{Op: structs.AND, DataCategoryCode: "customer_content_financial"},
+				structs.DataCategories{Op: structs.AND, QualifierCode: "identified_data", DataCategoryCode: "account_data"}),
+		}, plain}, []DroppedClause{
+			{"s0", 0, "", "customer_content", []string{"customer_content_genetic"}},
+		}},
+		{"clause dropped", func(d *NormalizedDocument) {
+			// account_data
+			d2, _ := removeStatement(d, 7)
+			*d = *d2
+		}, []structs.Statement{{
+			UseScopeCode: "capability", ActionCode: "provide", DataCategoryCode: "customer_content", TrackingID: "s0",
+			DataCategories: dataCategories(structs.DataCategories{Op: structs.EXCEPT, DataCategoryCode: "customer_content_health"}),
+		}, plain}, []DroppedClause{
+			{"s0", 1, "identified_data", "account_data", []string{"account_data"}},
+		}},
+		{"statement dropped", func(d *NormalizedDocument) {
+			d2, _ := removeStatement(d, 8)
+			*d = *d2
+		}, []structs.Statement{except}, []DroppedClause{
+			{"s1", 0, "", "provider_data", []string{"provider_data"}},
+		}},
+		{"qualifier narrowed", func(d *NormalizedDocument) {
+			d.Statements[7].QualifierCode = "pseudonymized_data"
+			d.Statements[8].QualifierCode = "pseudonymized_data"
+		}, []structs.Statement{{
+			UseScopeCode: "capability", ActionCode: "provide", DataCategoryCode: "customer_content", TrackingID: "s0",
+			DataCategories: dataCategories(
+				structs.DataCategories{Op: structs.AND, QualifierCode: "pseudonymized_data", DataCategoryCode: "account_data"},
+				structs.DataCategories{Op: structs.EXCEPT, DataCategoryCode: "customer_content_health"}),
+		}, {UseScopeCode: "capability", ActionCode: "provide", QualifierCode: "pseudonymized_data", DataCategoryCode: "provider_data", TrackingID: "s1"}}, []DroppedClause{}},
+		{"scope of a sub-statement changed", func(d *NormalizedDocument) {
+			d.Statements[7].ResultScopeCode = "service"
+		}, []structs.Statement{{
+			UseScopeCode: "capability", ActionCode: "provide", DataCategoryCode: "customer_content", TrackingID: "s0",
+			DataCategories: dataCategories(structs.DataCategories{Op: structs.EXCEPT, DataCategoryCode: "customer_content_health"}),
+		}, {
+			UseScopeCode: "capability", ResultScopeCode: "service", ActionCode: "provide", TrackingID: "s0-7",
+			QualifierCode: "identified_data", DataCategoryCode: "account_data", DataCategories: dataCategories(),
+		}, plain}, []DroppedClause{}},
+		{"omitted scope changed", func(d *NormalizedDocument) {
+			d.Statements[8].UseScopeCode = "service"
+		}, []structs.Statement{except, {
+			UseScopeCode: "service", SourceScopeCode: "capability", ResultScopeCode: "capability", ActionCode: "provide",
+			DataCategoryCode: "provider_data", TrackingID: "s1"}}, []DroppedClause{}},
+		{"statement added", func(d *NormalizedDocument) {
+			d.Statements = append(d.Statements, NormalizedStatement{Statement: structs.Statement{TrackingID: "new"}})
+		}, []structs.Statement{except, plain, {TrackingID: "new"}}, []DroppedClause{}},
+	}
+	for _, tt := range tests {
+		n, normDoc := testNormalizer(t, except, plain)
+		tt.edit(normDoc)
+		got, dropped := n.Denormalize(normDoc)
+		if !reflect.DeepEqual(got.Statements, tt.want) {
+			t.Errorf("%q. Denormalize() statements = %+v, want %+v", tt.name, got.Statements, tt.want)
+		}
+		if !reflect.DeepEqual(dropped, tt.dropped) {
+			t.Errorf("%q. Denormalize() dropped = %+v, want %+v", tt.name, dropped, tt.dropped)
+		}
+	}
+}
@@ -5,35 +5,123 @@
 package carneades
 
 import (
+	"fmt"
+	"sort"
+
 	"github.com/carneades/carneades-4/src/engine/caes"
 	"github.com/carneades/carneades-4/src/engine/terms"
+	"gopkg.in/yaml.v2"
 )
 
-//BoolValue explains a field in the StmtExplanation in more detail.
-// Value is true, when the field applies at all
+//BoolValue explains a predicate of the explanation schema for a statement in more detail.
+// Value is true, when the predicate applies at all
 //assumed is false, when the value of the Value field could be proven by carneades
+//Arguments are the arguments pro and con the predicate which decided its value
+//Statements are, for predicates relating statements, e.g. compatiblePurpose, the ids
+//of the statements the statement is related to
+//AssumptionSets are the ids of the assumption sets with facts the value relies on
 type BoolValue struct {
-	Value   bool `json:"value"`
-	Assumed bool `json:"assumed"` // if true assumed, otherwise proven
+	Value          bool                  `json:"value"`
+	Assumed        bool                  `json:"assumed"` // if true assumed, otherwise proven
+	Arguments      []ArgumentExplanation `json:"arguments,omitempty"`
+	Statements     []string              `json:"statements,omitempty"`
+	AssumptionSets []string              `json:"assumptionSets,omitempty"`
+}
+
+//ArgumentExplanation describes an argument of the argument graph pro or con a field of a StmtExplanation,
+//so that clients can explain e.g. that consent is required because the scheme pii4 applied to health data
+type ArgumentExplanation struct {
+	ID       string   `json:"id"`
+	Scheme   string   `json:"scheme"`          // id of the argument scheme instantiated by the argument
+	Title    string   `json:"title,omitempty"` // meta title of the scheme
+	Notes    string   `json:"notes,omitempty"` // meta notes of the scheme
+	Premises []string `json:"premises"`        // the statements the argument is based on
+	Weight   float64  `json:"weight"`          // the evaluated weight of the argument, 0 if it is not applicable
+	Pro      bool     `json:"pro"`             // true if the argument is pro the field, false if it is con
 }
 
-//StmtExplanation represents the Explanation for one Statement.
+//StmtExplanation represents the Explanation for one Statement, mapping the
+//predicates of the explanation schema of the rulebase to their values.
 //If the original statement has one or more and or except clauses,
 //a StmtExplanation represents only one of these clauses
-type StmtExplanation struct {
-	ConsentRequired             BoolValue `json:"consentRequired"`             // informed consent required
-	Pii                         BoolValue `json:"pii"`                         // personally identifiable information
-	Li                          BoolValue `json:"li"`                          // legitimate interest in the pii
-	CompatiblePurpose           []string  `json:"compatiblePurpose"`           // ids of statements with a proven compatible purpose
-	IDNotRequired               BoolValue `json:"idNotRequired"`               // identification of data subject is not required
-	TransferPii                 BoolValue `json:"transferPii"`                 // is there a location for a scope ans pii
-	ConsentRequired2TransferPii BoolValue `json:"consentRequired2TransferPii"` // informed consent required to transfer pii
-}
+type StmtExplanation map[string]BoolValue
 
 //Explanation contains the StmtExplanantions for each statement
 // keys are statement tracking ids
 type Explanation map[string]StmtExplanation
 
+//ExplanationPredicate is a predicate explained for each data use statement. Predicates
+//of arity 1 are properties of a statement, e.g. pii(S), predicates of arity 2 relate
+//a statement to other statements, e.g. compatiblePurpose(S1, S2).
+type ExplanationPredicate struct {
+	Predicate string `yaml:"predicate" json:"predicate"`
+	Default   bool   `yaml:"default" json:"default"` // the assumed value if the graph does not contain the predicate
+	Relation  bool   `yaml:"-" json:"relation"`      // true for predicates of arity 2
+}
+
+//ExplanationSchema lists the predicates explained for each statement by a rulebase. It is
+//declared in the meta data of the rulebase, e.g.
+//
+//	meta:
+//	  explanation_predicates:
+//	    - predicate: consentRequired
+//	      default: true
+//	    - predicate: compatiblePurpose
+//
+//If the rulebase declares no explanation predicates, the schema consists of the first
+//position of each issue scheme which is a predicate of a single statement, e.g. li for
+//li: [li(S), notLi(S)], with the default false.
+type ExplanationSchema []ExplanationPredicate
+
+//parseExplanationSchema returns the explanation schema of the YAML source of a rulebase, compiled into theory
+func parseExplanationSchema(src []byte, theory *caes.Theory) (ExplanationSchema, error) {
+	rb := struct {
+		Meta struct {
+			ExplanationPredicates ExplanationSchema `yaml:"explanation_predicates"`
+		}
+	}{}
+	if err := yaml.Unmarshal(src, &rb); err != nil {
+		return nil, err
+	}
+	if len(rb.Meta.ExplanationPredicates) == 0 {
+		return deriveExplanationSchema(theory), nil
+	}
+	schema := rb.Meta.ExplanationPredicates
+	for i, p := range schema {
+		_, unary := theory.Language[p.Predicate+"/1"]
+		_, binary := theory.Language[p.Predicate+"/2"]
+		if !unary && !binary {
+			return nil, fmt.Errorf("The explanation predicate %s is not declared in the language with arity 1 or 2", p.Predicate)
+		}
+		schema[i].Relation = !unary
+	}
+	return schema, nil
+}
+
+//deriveExplanationSchema derives the explanation schema of a theory from its issue schemes
+func deriveExplanationSchema(theory *caes.Theory) ExplanationSchema {
+	ids := make([]string, 0, len(theory.IssueSchemes))
+	for id := range theory.IssueSchemes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	schema := ExplanationSchema{}
+	for _, id := range ids {
+		is := theory.IssueSchemes[id]
+		if is == nil || len(*is) == 0 {
+			continue
+		}
+		t, ok := terms.ReadString((*is)[0])
+		if !ok {
+			continue
+		}
+		if c, ok := t.(terms.Compound); ok && len(c.Args) == 1 && c.Args[0].Type() == terms.VariableType {
+			schema = append(schema, ExplanationPredicate{Predicate: c.Functor})
+		}
+	}
+	return schema
+}
+
 func isDataUseStatement(t terms.Term) bool {
 	t2, ok := t.(terms.Compound)
 	if !ok {
@@ -48,121 +136,141 @@ func isDataUseStatement(t terms.Term) bool {
 }
 
 // stmtId: selects the id in a DUS term and returns
-// it as a string
+// it as the tracking id of the statement
 func stmtID(t terms.Compound) string {
-	return t.Args[9].String()
+	return decodeValue(t.Args[9])
 }
 
-// isTrue: check whether a given predicate is true/in for a particular
-// data use statement in the argument graph
-func isTrue(predicate string, dus terms.Compound, ag *caes.ArgGraph, defaultValue bool) BoolValue {
-	goal, ok := terms.ReadString(predicate + "(" + dus.String() + ")")
-	if !ok {
-		//fmt.Println("Improper predicate: " + predicate)
-		return BoolValue{defaultValue, true}
-	}
+// indexedStatement is a statement of an argument graph with its parsed atomic formula
+type indexedStatement struct {
+	term terms.Compound
+	stmt *caes.Statement
+}
+
+// statementIndex indexes the statements of an argument graph about data use statements
+// by the data use statement, their first argument, and then by their predicate,
+// so the formulas of the graph have to be parsed only once
+type statementIndex map[string]map[string][]indexedStatement
+
+func indexStatements(ag *caes.ArgGraph) statementIndex {
+	idx := make(statementIndex)
 	for wff, stmt := range ag.Statements {
 		t, ok := terms.ReadString(wff)
-		var b terms.Bindings
 		if !ok {
 			continue
 		}
-		_, ok = terms.Match(goal, t, b)
-		if ok {
-			v := stmt.Label == caes.In
-			//negation as failure, so no longer an assumption, no matter the value of v
-			return BoolValue{v, false}
+		c, ok := t.(terms.Compound)
+		if !ok || len(c.Args) == 0 {
+			continue
+		}
+		dus, ok := c.Args[0].(terms.Compound)
+		if !ok {
+			continue
+		}
+		key := dus.String()
+		if idx[key] == nil {
+			idx[key] = make(map[string][]indexedStatement)
 		}
+		idx[key][c.Functor] = append(idx[key][c.Functor], indexedStatement{c, stmt})
 	}
-	//fmt.Println("No match found: " + predicate + "(" + dus.String() + ")")
-	return BoolValue{defaultValue, true}
+	return idx
 }
 
-// ConsentRequired
-func cr(dus terms.Compound, ag *caes.ArgGraph) BoolValue {
-	return isTrue("consentRequired", dus, ag, true)
-}
-
-// Personally Identifiable Information
-func pii(dus terms.Compound, ag *caes.ArgGraph) BoolValue {
-	return isTrue("pii", dus, ag, true)
-}
-
-// Legitimate Interest
-func li(dus terms.Compound, ag *caes.ArgGraph) BoolValue {
-	return isTrue("li", dus, ag, false)
-}
-
-// Returns a slice of statements ids, for statements having a purpose
-// compatible with the statement represented by wff
-func cp(dus terms.Compound, ag *caes.ArgGraph) []string {
-	// find the li statement for this dus
-	goal, ok := terms.ReadString("compatiblePurpose(" + dus.String() + ",X)")
-	if !ok {
-		return []string{}
-	}
-	result := []string{}
-	for wff, stmt := range ag.Statements {
-		t, ok := terms.ReadString(wff)
-		var b terms.Bindings
-		if !ok {
-			continue
+// explain explains the predicate for a particular data use statement in the argument graph.
+// A property is true if its statement is in, a relation is true if the statement is related
+// to any statement by an in statement.
+func (idx statementIndex) explain(p ExplanationPredicate, dus terms.Compound) BoolValue {
+	v := BoolValue{Value: p.Default, Assumed: true}
+	for _, s := range idx[dus.String()][p.Predicate] {
+		if !p.Relation && len(s.term.Args) == 1 {
+			//negation as failure, so no longer an assumption, no matter the value of v
+			return BoolValue{Value: s.stmt.Label == caes.In, Assumed: false, Arguments: explainStatement(s.stmt), AssumptionSets: reliedAssumptionSets(s.stmt)}
 		}
-		_, ok = terms.Match(goal, t, b)
-		if ok {
-			if stmt.Label == caes.In {
-				dus2 := t.(terms.Compound).Args[1].(terms.Compound)
-				result = append(result, stmtID(dus2))
+		if p.Relation && len(s.term.Args) == 2 {
+			if v.Assumed {
+				v = BoolValue{Value: false, Assumed: false, Arguments: []ArgumentExplanation{}, Statements: []string{}}
+			}
+			v.Arguments = append(v.Arguments, explainStatement(s.stmt)...)
+			v.AssumptionSets = mergeStrings(v.AssumptionSets, reliedAssumptionSets(s.stmt))
+			if dus2, ok := s.term.Args[1].(terms.Compound); ok && s.stmt.Label == caes.In && len(dus2.Args) > 9 {
+				v.Value = true
+				v.Statements = append(v.Statements, stmtID(dus2))
 			}
 		}
 	}
-	return result
+	return v
 }
 
-// Returns a slice of ids for statements not
-// requring the identification of the data subject
-func idnr(dus terms.Compound, ag *caes.ArgGraph) BoolValue {
-	return isTrue("idNotRequired", dus, ag, false)
+// mergeStrings returns the sorted union of the sorted lists a and b,
+// or nil if both are empty
+func mergeStrings(a []string, b []string) []string {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	m := make(map[string]bool, len(a)+len(b))
+	for _, s := range append(append([]string{}, a...), b...) {
+		m[s] = true
+	}
+	l := make([]string, 0, len(m))
+	for s := range m {
+		l = append(l, s)
+	}
+	sort.Strings(l)
+	return l
 }
 
-func tpii(dus terms.Compound, ag *caes.ArgGraph) BoolValue {
-	return isTrue("transferPii", dus, ag, false)
+// explainStatement returns the arguments pro the statement and the arguments
+// pro the other positions of its issue, which are con the statement
+func explainStatement(stmt *caes.Statement) []ArgumentExplanation {
+	args := []ArgumentExplanation{}
+	for _, arg := range stmt.Args {
+		args = append(args, explainArgument(arg, true))
+	}
+	if stmt.Issue != nil {
+		for _, pos := range stmt.Issue.Positions {
+			if pos == stmt {
+				continue
+			}
+			for _, arg := range pos.Args {
+				args = append(args, explainArgument(arg, false))
+			}
+		}
+	}
+	return args
 }
 
-func cr2tpii(dus terms.Compound, ag *caes.ArgGraph) BoolValue {
-	return isTrue("consentRequired2TransferPii", dus, ag, false)
+func explainArgument(arg *caes.Argument, pro bool) ArgumentExplanation {
+	e := ArgumentExplanation{ID: arg.Id, Premises: []string{}, Weight: arg.Weight, Pro: pro}
+	if arg.Scheme != nil {
+		e.Scheme = arg.Scheme.Id
+		e.Title, _ = arg.Scheme.Metadata["title"].(string)
+		e.Notes, _ = arg.Scheme.Metadata["notes"].(string)
+	}
+	for _, p := range arg.Premises {
+		if p.Stmt != nil {
+			e.Premises = append(e.Premises, decodeStatement(p.Stmt.Id))
+		}
+	}
+	return e
 }
 
 //GetExplanation returns the Explanation struct filled with explanations for each Statement
-func (c ComplianceChecker) GetExplanation(theory *caes.Theory, ag *caes.ArgGraph) (Explanation, error) {
+func (c ComplianceChecker) GetExplanation(theory *Theory, ag *caes.ArgGraph) (Explanation, error) {
+	schema := theory.Schema
 	m := make(map[string]StmtExplanation)
-	for wff := range ag.Statements {
-		t, ok := terms.ReadString(wff)
-		if !ok {
-			continue
-		}
-
-		if isDataUseStatement(t) {
-			dus := t.(terms.Compound).Args[0].(terms.Compound)
-			m[stmtID(dus)] = StmtExplanation{
-				ConsentRequired:             cr(dus, ag),
-				Pii:                         pii(dus, ag),
-				Li:                          li(dus, ag),
-				CompatiblePurpose:           cp(dus, ag),
-				IDNotRequired:               idnr(dus, ag),
-				TransferPii:                 tpii(dus, ag),
-				ConsentRequired2TransferPii: cr2tpii(dus, ag),
+	idx := indexStatements(ag)
+	for _, stmts := range idx {
+		for _, s := range stmts["dataUseStatement"] {
+			if !isDataUseStatement(s.term) {
+				continue
+			}
+			dus := s.term.Args[0].(terms.Compound)
+			e := make(StmtExplanation, len(schema))
+			for _, p := range schema {
+				e[p.Predicate] = idx.explain(p, dus)
 			}
+			m[stmtID(dus)] = e
 		}
 	}
-	// Begin DEBUG
-	//	b, err := json.Marshal(m)
-	//	if err != nil {
-	//		fmt.Println("error:", err)
-	//	} else {
-	//		// os.Stdout.Write(b)
-	//		fmt.Printf("%s\n", b)
-	//	}
-	// End DEBUG
 	return m, nil
 }
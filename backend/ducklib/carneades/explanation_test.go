@@ -0,0 +1,123 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComplianceChecker_GetExplanation(t *testing.T) {
+	c := MakeComplianceChecker()
+	theory, err := c.GetTheory("test", "test", strings.NewReader(testRuleBase))
+	if err != nil {
+		t.Fatalf("GetTheory() error = %v", err)
+	}
+	_, exp, err := c.IsCompliant(theory, actionDoc("market", "provide"))
+	if err != nil {
+		t.Fatalf("IsCompliant() error = %v", err)
+	}
+
+	market := exp["s0"]["consentRequired"]
+	if !market.Value || market.Assumed || len(market.Arguments) != 1 {
+		t.Fatalf("GetExplanation() consentRequired = %+v, want one argument", market)
+	}
+	arg := market.Arguments[0]
+	if arg.Scheme != "market" || arg.Title != "Marketing" || arg.Notes != "Marketing requires consent" || !arg.Pro || arg.Weight == 0 {
+		t.Errorf("GetExplanation() argument = %+v", arg)
+	}
+	if len(arg.Premises) != 1 || !strings.HasPrefix(arg.Premises[0], "dataUseStatement(") {
+		t.Errorf("GetExplanation() premises = %v", arg.Premises)
+	}
+
+	// the default of the explanation predicate is assumed
+	if provide := exp["s1"]["consentRequired"]; !provide.Value || !provide.Assumed || len(provide.Arguments) != 0 {
+		t.Errorf("GetExplanation() consentRequired = %+v, want an assumption without arguments", provide)
+	}
+	if len(exp["s1"]) != 1 {
+		t.Errorf("GetExplanation() = %v, want only the explanation predicates of the rulebase", exp["s1"])
+	}
+}
+
+func TestComplianceChecker_GetExplanation_evicted(t *testing.T) {
+	c := MakeComplianceChecker()
+	theory, err := c.GetTheory("test", "test", strings.NewReader(testRuleBase))
+	if err != nil {
+		t.Fatalf("GetTheory() error = %v", err)
+	}
+	want := ExplanationSchema{{Predicate: "consentRequired", Default: true}}
+
+	// the theory is still held, e.g. by a session, when it leaves the cache
+	c.Theories.Remove("test")
+	_, exp, err := c.IsCompliant(theory, actionDoc("provide"))
+	if err != nil {
+		t.Fatalf("IsCompliant() error = %v", err)
+	}
+	if _, ok := exp["s0"]["consentRequired"]; !ok || len(exp["s0"]) != len(want) {
+		t.Errorf("GetExplanation() of an evicted theory = %v, want the predicates %v", exp["s0"], want)
+	}
+
+	recompiled, err := c.GetTheory("test", "test", strings.NewReader(testRuleBase))
+	if err != nil {
+		t.Fatalf("GetTheory() error = %v", err)
+	}
+	if recompiled == theory {
+		t.Fatalf("GetTheory() returned the evicted theory")
+	}
+	if !reflect.DeepEqual(theory.Schema, want) || !reflect.DeepEqual(recompiled.Schema, want) {
+		t.Errorf("Schema = %v and %v after recompiling, want %v", theory.Schema, recompiled.Schema, want)
+	}
+}
+
+func TestParseExplanationSchema(t *testing.T) {
+	theory := testTheory(t)
+	tests := []struct {
+		name    string
+		src     string
+		want    ExplanationSchema
+		wantErr bool
+	}{
+		{"declared", testRuleBase, ExplanationSchema{{Predicate: "consentRequired", Default: true}}, false},
+		{"relation", "meta:\n  explanation_predicates:\n    - predicate: dataUseStatement\n", ExplanationSchema{{Predicate: "dataUseStatement"}}, false},
+		{"undeclared", "meta:\n  explanation_predicates:\n    - predicate: li\n", nil, true},
+		{"derived from the issue schemes", "meta:\n  id: test\n", ExplanationSchema{}, false},
+	}
+	for _, tt := range tests {
+		got, err := parseExplanationSchema([]byte(tt.src), theory.Theory)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q. parseExplanationSchema() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q. parseExplanationSchema() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFoldExplanation(t *testing.T) {
+	a := ArgumentExplanation{ID: "a1", Scheme: "market", Pro: true}
+	b := ArgumentExplanation{ID: "a2", Scheme: "share", Pro: true}
+	exp := Explanation{
+		"s1-1": StmtExplanation{
+			"consentRequired":   BoolValue{Value: true, Arguments: []ArgumentExplanation{a}},
+			"compatiblePurpose": BoolValue{Value: true, Statements: []string{"s1-2", "s2"}},
+		},
+		"s1-2": StmtExplanation{
+			"consentRequired":   BoolValue{Value: false, Arguments: []ArgumentExplanation{b}},
+			"compatiblePurpose": BoolValue{Value: true, Statements: []string{"s1-1", "s3-1"}},
+			"pii":               BoolValue{Value: true, Assumed: true},
+		},
+	}
+	got := FoldExplanation(exp)["s1"]
+	if cr := got["consentRequired"]; !cr.Value || cr.Assumed || len(cr.Arguments) != 2 {
+		t.Errorf("FoldExplanation() consentRequired = %+v, want the arguments of both clauses", cr)
+	}
+	if cp := got["compatiblePurpose"]; !reflect.DeepEqual(cp.Statements, []string{"s2", "s3"}) && !reflect.DeepEqual(cp.Statements, []string{"s3", "s2"}) {
+		t.Errorf("FoldExplanation() compatiblePurpose = %v, want s2 and s3", cp.Statements)
+	}
+	if pii := got["pii"]; !pii.Value || !pii.Assumed {
+		t.Errorf("FoldExplanation() pii = %+v", pii)
+	}
+}
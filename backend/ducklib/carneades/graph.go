@@ -0,0 +1,180 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/carneades/carneades-4/src/engine/caes"
+	"github.com/carneades/carneades-4/src/engine/caes/encoding/graphml"
+	y "github.com/carneades/carneades-4/src/engine/caes/encoding/yaml"
+)
+
+// Formats in which an argument graph can be exported with ExportGraph
+const (
+	GraphML = "graphml"
+	DOT     = "dot"
+	YAML    = "yaml"
+)
+
+// GraphContentTypes maps each supported export format to the MIME type
+// of its output
+var GraphContentTypes = map[string]string{
+	GraphML: "application/graphml+xml",
+	DOT:     "text/vnd.graphviz",
+	YAML:    "application/x-yaml",
+}
+
+// the graphml and yaml encoders and the yaml decoder keep their state in
+// package variables, so concurrent exports and imports have to be serialized
+var encoderLock sync.Mutex
+
+// ExportGraph writes the labelled argument graph ag to w, using the given format.
+// An error is returned if the format is not supported or the graph cannot be encoded.
+func ExportGraph(w io.Writer, ag *caes.ArgGraph, format string) error {
+	switch format {
+	case GraphML:
+		encoderLock.Lock()
+		defer encoderLock.Unlock()
+		return graphml.Export(w, ag)
+	case YAML:
+		encoderLock.Lock()
+		defer encoderLock.Unlock()
+		y.Export(w, ag)
+		return nil
+	case DOT:
+		return ExportDot(w, ag)
+	default:
+		return fmt.Errorf("unsupported graph format: %s", format)
+	}
+}
+
+// colors of the statement labels, the same as used by the graphml encoder
+var dotLabelColors = map[caes.Label]string{
+	caes.In:        "#3AB54A",
+	caes.Out:       "#FF0000",
+	caes.Undecided: "#FCEE21",
+}
+
+// dotString quotes s as a DOT string literal
+func dotString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+	return `"` + r.Replace(s) + `"`
+}
+
+// ExportDot writes the argument graph ag to w in the DOT language of Graphviz.
+// Statements are drawn as boxes filled with the color of their label, with assumptions
+// drawn bold, arguments as ellipses labelled with their scheme and issues as hexagons.
+// Nodes and edges are written in a stable order, so that exporting the same graph
+// twice yields the same output.
+func ExportDot(w io.Writer, ag *caes.ArgGraph) error {
+	assums := caes.SliceToMap(ag.Assumptions)
+
+	stmtKeys := make([]string, 0, len(ag.Statements))
+	for k := range ag.Statements {
+		stmtKeys = append(stmtKeys, k)
+	}
+	sort.Strings(stmtKeys)
+
+	argKeys := make([]string, 0, len(ag.Arguments))
+	for k := range ag.Arguments {
+		argKeys = append(argKeys, k)
+	}
+	sort.Strings(argKeys)
+
+	issueKeys := make([]string, 0, len(ag.Issues))
+	for k := range ag.Issues {
+		issueKeys = append(issueKeys, k)
+	}
+	sort.Strings(issueKeys)
+
+	var err error
+	p := func(format string, a ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, a...)
+		}
+	}
+
+	p("digraph G {\n")
+	p("  rankdir=BT;\n")
+	p("  node [fontname=\"Dialog\", fontsize=12];\n")
+
+	// statements
+	stmtNodes := make(map[*caes.Statement]string)
+	for i, k := range stmtKeys {
+		stmt := ag.Statements[k]
+		id := fmt.Sprintf("s%d", i+1)
+		stmtNodes[stmt] = id
+		text := stmt.Text
+		if text == "" {
+			text = stmt.Id
+		}
+		penwidth := "1.0"
+		if assums[stmt.Id] || assums[k] {
+			penwidth = "3.0"
+		}
+		p("  %s [shape=box, style=filled, fillcolor=%s, penwidth=%s, label=%s, tooltip=%s];\n",
+			id, dotString(dotLabelColors[stmt.Label]), penwidth, dotString(text), dotString(stmt.Label.String()))
+	}
+
+	// the node of a statement referenced by an argument or issue,
+	// which may be missing from the statements of the graph
+	stmtNode := func(stmt *caes.Statement) string {
+		if id, ok := stmtNodes[stmt]; ok {
+			return id
+		}
+		id := fmt.Sprintf("s%d", len(stmtNodes)+1)
+		stmtNodes[stmt] = id
+		p("  %s [shape=box, style=dashed, label=%s];\n", id, dotString(stmt.Id))
+		return id
+	}
+
+	// arguments
+	for i, k := range argKeys {
+		arg := ag.Arguments[k]
+		id := fmt.Sprintf("a%d", i+1)
+		label := arg.Id
+		if arg.Scheme != nil {
+			label = fmt.Sprintf("%s: %s", arg.Id, arg.Scheme.Id)
+		}
+		p("  %s [shape=ellipse, label=%s];\n", id, dotString(label))
+		for _, prem := range arg.Premises {
+			if prem.Stmt == nil {
+				continue
+			}
+			p("  %s -> %s [arrowhead=none, label=%s];\n", stmtNode(prem.Stmt), id, dotString(prem.Role))
+		}
+		if arg.Conclusion != nil {
+			p("  %s -> %s [label=%s];\n", id, stmtNode(arg.Conclusion), dotString(fmt.Sprintf("%.2f", arg.Weight)))
+		}
+		if arg.Undercutter != nil {
+			p("  %s -> %s [arrowhead=none, style=dashed, penwidth=2.0];\n", stmtNode(arg.Undercutter), id)
+		}
+	}
+
+	// issues
+	for i, k := range issueKeys {
+		issue := ag.Issues[k]
+		id := fmt.Sprintf("i%d", i+1)
+		std := "PE"
+		switch issue.Standard {
+		case caes.CCE:
+			std = "CCE"
+		case caes.BRD:
+			std = "BRD"
+		}
+		p("  %s [shape=hexagon, label=%s];\n", id, dotString(fmt.Sprintf("%s: %s", issue.Id, std)))
+		for _, pos := range issue.Positions {
+			p("  %s -> %s [arrowhead=none];\n", stmtNode(pos), id)
+		}
+	}
+
+	p("}\n")
+	return err
+}
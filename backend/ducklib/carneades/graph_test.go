@@ -0,0 +1,91 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/carneades/carneades-4/src/engine/caes"
+)
+
+func testGraph() *caes.ArgGraph {
+	ag := caes.NewArgGraph()
+	p := &caes.Statement{Id: "pii(s1)", Text: "pii(s1)", Label: caes.In}
+	q := &caes.Statement{Id: "consentRequired(s1)", Text: `say "yes"`, Label: caes.Out}
+	u := &caes.Statement{Id: "undercut(a1)", Label: caes.Undecided}
+	ag.Statements[p.Id] = p
+	ag.Statements[q.Id] = q
+	ag.Statements[u.Id] = u
+	ag.Assumptions = []string{p.Id}
+	arg := &caes.Argument{
+		Id:          "a1",
+		Scheme:      &caes.Scheme{Id: "cr1"},
+		Premises:    []caes.Premise{{Stmt: p, Role: "major"}},
+		Conclusion:  q,
+		Undercutter: u,
+		Weight:      0.5,
+	}
+	ag.Arguments[arg.Id] = arg
+	q.Args = []*caes.Argument{arg}
+	return ag
+}
+
+func TestExportDot(t *testing.T) {
+	ag := testGraph()
+	var b1, b2 bytes.Buffer
+	if err := ExportDot(&b1, ag); err != nil {
+		t.Fatalf("ExportDot() error = %v", err)
+	}
+	if err := ExportDot(&b2, ag); err != nil {
+		t.Fatalf("ExportDot() error = %v", err)
+	}
+	if b1.String() != b2.String() {
+		t.Errorf("ExportDot() is not deterministic:\n%s\n%s", b1.String(), b2.String())
+	}
+	out := b1.String()
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"header", "digraph G {"},
+		{"quoted label", `label="say \"yes\""`},
+		{"assumption", `penwidth=3.0, label="pii(s1)"`},
+		{"argument", `a1 [shape=ellipse, label="a1: cr1"];`},
+		{"premise", `s2 -> a1 [arrowhead=none, label="major"];`},
+		{"conclusion", `a1 -> s1 [label="0.50"];`},
+		{"undercutter", `s3 -> a1 [arrowhead=none, style=dashed, penwidth=2.0];`},
+	}
+	for _, tt := range tests {
+		if !strings.Contains(out, tt.want) {
+			t.Errorf("%q. ExportDot() = %s, want it to contain %s", tt.name, out, tt.want)
+		}
+	}
+}
+
+func TestExportGraph(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{"graphml", GraphML, "<graphml", false},
+		{"dot", DOT, "digraph G {", false},
+		{"yaml", YAML, "statements:", false},
+		{"unknown format", "svg", "", true},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		err := ExportGraph(&buf, testGraph(), tt.format)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q. ExportGraph() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("%q. ExportGraph() = %s, want it to contain %s", tt.name, buf.String(), tt.want)
+		}
+	}
+}
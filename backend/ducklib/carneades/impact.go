@@ -0,0 +1,220 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+)
+
+// ImpactReport is the result of checking documents against the current and a candidate
+// version of a rulebase. Impacts lists the documents whose verdict or explanation
+// changed and the documents which could not be checked, ordered by document id.
+type ImpactReport struct {
+	RuleBaseID         string           `json:"rulebaseId"`
+	CurrentRevision    string           `json:"currentRevision"`
+	CandidateRevision  string           `json:"candidateRevision"`
+	Documents          int              `json:"documents"`          // number of documents checked
+	VerdictChanges     int              `json:"verdictChanges"`     // number of documents whose verdict changed
+	ExplanationChanges int              `json:"explanationChanges"` // number of documents whose explanation changed
+	Errors             int              `json:"errors"`             // number of documents which could not be checked
+	Impacts            []DocumentImpact `json:"impacts"`
+}
+
+// DocumentImpact describes how the results of checking a document change with the
+// candidate version of a rulebase. Error is set if the document could not be checked.
+type DocumentImpact struct {
+	DocumentID string              `json:"documentId"`
+	Name       string              `json:"name"`
+	Before     string              `json:"before,omitempty"` // the verdict under the current rulebase, COMPLIANT, NON_COMPLIANT or UNDECIDED
+	After      string              `json:"after,omitempty"`  // the verdict under the candidate rulebase
+	Changes    []ExplanationChange `json:"changes,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// VerdictChanged reports whether the document was checked and its verdict changed
+func (d DocumentImpact) VerdictChanged() bool {
+	return d.Error == "" && d.Before != d.After
+}
+
+// ExplanationChange is a change of the explanation of a predicate for a statement. Before
+// or After is nil if the current or candidate rulebase does not explain the predicate.
+// The arguments of the explanations are omitted.
+type ExplanationChange struct {
+	TrackingID string     `json:"trackingId"`
+	Predicate  string     `json:"predicate"`
+	Before     *BoolValue `json:"before"`
+	After      *BoolValue `json:"after"`
+}
+
+// ImpactAnalysis checks the documents, normalized with normalize, against the loaded
+// version of a rulebase and against the candidate version src of the rulebase, with
+// the same meta id. The candidate is compiled by a separate checker, so the loaded
+// version is neither replaced nor evicted from the cache. The documents are checked
+// concurrently, bounded by the number of search workers.
+func (c *ComplianceCheckerPlugin) ImpactAnalysis(src []byte, docs []structs.Document, normalize func(structs.Document) (*NormalizedDocument, error)) (*ImpactReport, error) {
+	desc, err := parseRuleBaseDescription(src)
+	if err != nil {
+		return nil, err
+	}
+	current, ok := c.RuleBaseDescriptions()[desc.ID]
+	if !ok {
+		return nil, structs.NewHTTPError(fmt.Sprintf("Rulebase %s not found", desc.ID), http.StatusNotFound)
+	}
+	currentTheory, err := c.theory(desc.ID)
+	if err != nil {
+		return nil, err
+	}
+	candidate := MakeComplianceChecker()
+	candidate.Search = c.checker.Search
+	candidateTheory, err := compileTheory(candidate, desc, src)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := c.checker.Search.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	impacts := make([]DocumentImpact, len(docs))
+	for i, doc := range docs {
+		wg.Add(1)
+		go func(i int, doc structs.Document) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			impacts[i] = documentImpact(doc, normalize, c.checker, currentTheory, candidate, candidateTheory)
+		}(i, doc)
+	}
+	wg.Wait()
+
+	report := &ImpactReport{
+		RuleBaseID:        desc.ID,
+		CurrentRevision:   current.Revision,
+		CandidateRevision: desc.Revision,
+		Documents:         len(docs),
+		Impacts:           []DocumentImpact{},
+	}
+	for _, impact := range impacts {
+		switch {
+		case impact.Error != "":
+			report.Errors++
+		case impact.VerdictChanged():
+			report.VerdictChanges++
+		}
+		if len(impact.Changes) > 0 {
+			report.ExplanationChanges++
+		}
+		if impact.Error != "" || impact.VerdictChanged() || len(impact.Changes) > 0 {
+			report.Impacts = append(report.Impacts, impact)
+		}
+	}
+	sort.Slice(report.Impacts, func(i, j int) bool { return report.Impacts[i].DocumentID < report.Impacts[j].DocumentID })
+	return report, nil
+}
+
+// documentImpact checks the document against the current and the candidate theory
+func documentImpact(doc structs.Document, normalize func(structs.Document) (*NormalizedDocument, error),
+	checker *ComplianceChecker, current *Theory, candidate *ComplianceChecker, candidateTheory *Theory) DocumentImpact {
+
+	impact := DocumentImpact{DocumentID: doc.ID, Name: doc.Name}
+	normDoc, err := normalize(doc)
+	if err != nil {
+		impact.Error = fmt.Sprintf("Could not normalize the document: %s", err)
+		return impact
+	}
+	before, err := checker.Check(current, normDoc)
+	if err != nil {
+		impact.Error = fmt.Sprintf("Could not check the document against the current rulebase: %s", err)
+		return impact
+	}
+	after, err := candidate.Check(candidateTheory, normDoc)
+	if err != nil {
+		impact.Error = fmt.Sprintf("Could not check the document against the candidate rulebase: %s", err)
+		return impact
+	}
+	impact.Before, impact.After = string(before.Verdict), string(after.Verdict)
+	impact.Changes = explanationChanges(FoldExplanation(before.Explanation), FoldExplanation(after.Explanation))
+	return impact
+}
+
+// explanationChanges returns the changes of the values, assumptions and related statements
+// of the predicates explained for each statement, ordered by tracking id and predicate
+func explanationChanges(before Explanation, after Explanation) []ExplanationChange {
+	changes := []ExplanationChange{}
+	for _, id := range unionKeys(before, after) {
+		b, a := before[id], after[id]
+		predicates := make(map[string]bool)
+		for p := range b {
+			predicates[p] = true
+		}
+		for p := range a {
+			predicates[p] = true
+		}
+		sorted := make([]string, 0, len(predicates))
+		for p := range predicates {
+			sorted = append(sorted, p)
+		}
+		sort.Strings(sorted)
+		for _, p := range sorted {
+			bv, inBefore := b[p]
+			av, inAfter := a[p]
+			if inBefore && inAfter && !boolValueChanged(bv, av) {
+				continue
+			}
+			change := ExplanationChange{TrackingID: id, Predicate: p}
+			if inBefore {
+				bv.Arguments = nil
+				change.Before = &bv
+			}
+			if inAfter {
+				av.Arguments = nil
+				change.After = &av
+			}
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+func unionKeys(a Explanation, b Explanation) []string {
+	keys := make(map[string]bool)
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	l := make([]string, 0, len(keys))
+	for k := range keys {
+		l = append(l, k)
+	}
+	sort.Strings(l)
+	return l
+}
+
+// boolValueChanged reports whether the value, the assumption or the related
+// statements of the explanation of a predicate differ, ignoring the arguments
+func boolValueChanged(a BoolValue, b BoolValue) bool {
+	if a.Value != b.Value || a.Assumed != b.Assumed || len(a.Statements) != len(b.Statements) {
+		return true
+	}
+	as := append([]string{}, a.Statements...)
+	bs := append([]string{}, b.Statements...)
+	sort.Strings(as)
+	sort.Strings(bs)
+	for i := range as {
+		if as[i] != bs[i] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+)
+
+func impactDoc(id string, actions ...string) structs.Document {
+	d := structs.Document{ID: id, Name: "document " + id}
+	for _, a := range actions {
+		d.Statements = append(d.Statements, structs.Statement{ActionCode: a})
+	}
+	return d
+}
+
+func TestComplianceCheckerPlugin_ImpactAnalysis(t *testing.T) {
+	c, dir := testPlugin(t)
+	defer os.RemoveAll(dir)
+
+	docs := []structs.Document{
+		impactDoc("d3", "share", "collect"),
+		impactDoc("d1", "market"),
+		impactDoc("d2", "provide"),
+		impactDoc("d4", "market"),
+	}
+	normalize := func(doc structs.Document) (*NormalizedDocument, error) {
+		if doc.ID == "d4" {
+			return nil, errors.New("broken")
+		}
+		return testNormalize(doc)
+	}
+
+	// the candidate does not require consent for marketing
+	report, err := c.ImpactAnalysis(testRuleBaseWithID("test", false), docs, normalize)
+	if err != nil {
+		t.Fatalf("ImpactAnalysis() error = %v", err)
+	}
+	if report.Documents != 4 || report.VerdictChanges != 1 || report.ExplanationChanges != 1 || report.Errors != 1 {
+		t.Errorf("ImpactAnalysis() summary = %d documents, %d verdicts, %d explanations, %d errors, want 4, 1, 1, 1",
+			report.Documents, report.VerdictChanges, report.ExplanationChanges, report.Errors)
+	}
+	if report.CurrentRevision == report.CandidateRevision || report.CandidateRevision != ruleBaseRevision(testRuleBaseWithID("test", false)) {
+		t.Errorf("ImpactAnalysis() revisions = %s, %s", report.CurrentRevision, report.CandidateRevision)
+	}
+	ids := []string{}
+	for _, d := range report.Impacts {
+		ids = append(ids, d.DocumentID)
+	}
+	if !reflect.DeepEqual(ids, []string{"d1", "d4"}) {
+		t.Fatalf("ImpactAnalysis() impacts = %v, want [d1 d4]", ids)
+	}
+	d1 := report.Impacts[0]
+	if !d1.VerdictChanged() || d1.Before != "NON_COMPLIANT" || d1.After != "COMPLIANT" {
+		t.Errorf("ImpactAnalysis() d1 verdict = %s -> %s", d1.Before, d1.After)
+	}
+	// consentRequired is no longer proven, but assumed by default
+	want := []ExplanationChange{{TrackingID: "s0", Predicate: "consentRequired", Before: &BoolValue{Value: true}, After: &BoolValue{Value: true, Assumed: true}}}
+	if !reflect.DeepEqual(d1.Changes, want) {
+		t.Errorf("ImpactAnalysis() d1 changes = %+v, want %+v", d1.Changes, want)
+	}
+	if d4 := report.Impacts[1]; d4.Error == "" || d4.VerdictChanged() {
+		t.Errorf("ImpactAnalysis() d4 = %+v, want an error", d4)
+	}
+
+	// the loaded version of the rulebase is still used
+	if ok, _, err := c.IsCompliant("test", actionDoc("market")); ok || err != nil {
+		t.Errorf("IsCompliant() after ImpactAnalysis() = %v, %v, want false", ok, err)
+	}
+
+	tests := []struct {
+		name       string
+		src        []byte
+		wantStatus int
+	}{
+		{"unknown rulebase", testRuleBaseWithID("other", false), http.StatusNotFound},
+		{"no meta id", []byte("meta:\n  title: no id\n"), http.StatusBadRequest},
+		{"invalid", []byte("meta:\n  id: test\nargument_schemes: [\n"), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if _, err := c.ImpactAnalysis(tt.src, docs, normalize); httpStatus(err) != tt.wantStatus {
+			t.Errorf("%q. ImpactAnalysis() error = %v, want status %v", tt.name, err, tt.wantStatus)
+		}
+	}
+}
+
+func Test_explanationChanges(t *testing.T) {
+	before := Explanation{
+		"s1": {"pii": {Value: true}, "li": {Value: false, Assumed: true}},
+		"s2": {"compatiblePurpose": {Value: true, Statements: []string{"s1", "s3"}}},
+	}
+	after := Explanation{
+		"s1": {"pii": {Value: true, Arguments: []ArgumentExplanation{{ID: "a1"}}}, "li": {Value: false}},
+		"s2": {"compatiblePurpose": {Value: true, Statements: []string{"s3", "s1"}}, "transferPii": {Value: true}},
+	}
+	got := explanationChanges(before, after)
+	want := []ExplanationChange{
+		{TrackingID: "s1", Predicate: "li", Before: &BoolValue{Value: false, Assumed: true}, After: &BoolValue{Value: false}},
+		{TrackingID: "s2", Predicate: "transferPii", After: &BoolValue{Value: true}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("explanationChanges() = %+v, want %+v", got, want)
+	}
+}
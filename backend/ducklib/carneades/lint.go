@@ -0,0 +1,349 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/carneades/carneades-4/src/engine/terms"
+	"gopkg.in/yaml.v2"
+)
+
+// Severities of LintIssues. A rulebase with errors cannot be used to check documents.
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// LintIssue is a problem found in a rulebase by LintRuleBase
+type LintIssue struct {
+	Severity string `json:"severity"`
+	Scheme   string `json:"scheme,omitempty"` // the id of the argument or issue scheme with the problem, if any
+	Line     int    `json:"line,omitempty"`   // the line of the problem or of its scheme in the source, if known
+	Message  string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	prefix := i.Severity
+	if i.Line > 0 {
+		prefix = fmt.Sprintf("line %d: %s", i.Line, prefix)
+	}
+	if i.Scheme != "" {
+		return fmt.Sprintf("%s: %s: %s", prefix, i.Scheme, i.Message)
+	}
+	return fmt.Sprintf("%s: %s", prefix, i.Message)
+}
+
+// yamlErrorLine matches the line number in the errors of the yaml parser
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// errorLine returns the line number in an error of the yaml parser, or 0
+func errorLine(err error) int {
+	m := yamlErrorLine.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0
+	}
+	line, _ := strconv.Atoi(m[1])
+	return line
+}
+
+var (
+	yamlSection  = regexp.MustCompile(`^([A-Za-z_]+):`)
+	yamlSchemeID = regexp.MustCompile(`^\s*(?:-\s*)?id:\s*['"]?([^'"#\s]+)`)
+	yamlIssueKey = regexp.MustCompile(`^\s+['"]?([^'"#\s:-][^'"#\s:]*)['"]?\s*:`)
+)
+
+// schemeLines returns the lines of the ids of the argument schemes and of the
+// keys of the issue schemes in the YAML source of a rulebase, mapped to the ids
+func schemeLines(src []byte) map[string]int {
+	lines := make(map[string]int)
+	section := ""
+	for i, l := range strings.Split(string(src), "\n") {
+		if m := yamlSection.FindStringSubmatch(l); m != nil {
+			section = m[1]
+			continue
+		}
+		var m []string
+		switch section {
+		case "argument_schemes":
+			m = yamlSchemeID.FindStringSubmatch(l)
+		case "issue_schemes":
+			m = yamlIssueKey.FindStringSubmatch(l)
+		}
+		if m != nil {
+			if _, ok := lines[m[1]]; !ok {
+				lines[m[1]] = i + 1
+			}
+		}
+	}
+	return lines
+}
+
+// LintResult is the result of linting a rulebase. The rulebase is valid iff
+// none of its issues is an error.
+type LintResult struct {
+	Valid  bool        `json:"valid"`
+	Issues []LintIssue `json:"issues"`
+}
+
+// lintRuleBase is the part of a rulebase checked by LintRuleBase
+type lintRuleBase struct {
+	Meta struct {
+		ID                    string            `yaml:"id"`
+		ExplanationPredicates ExplanationSchema `yaml:"explanation_predicates"`
+	}
+	Language         map[string]string
+	Issue_schemes    map[string][]string
+	Argument_schemes []struct {
+		ID          string `yaml:"id"`
+		Premises    []string
+		Assumptions []string
+		Exceptions  []string
+		Deletions   []string
+		Conclusions []string
+	}
+}
+
+// mandatoryStatements must be declared and concluded by some argument
+// scheme of every rulebase, since they are used to check compliance
+var mandatoryStatements = []string{"notDocConsentRequired", "docConsentRequired"}
+
+// RuleBaseIDs returns the meta ids of the rulebase files in the directory,
+// mapped to their file names. Test files and files which cannot be parsed are ignored.
+func RuleBaseIDs(dir string) (map[string]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]string)
+	for _, file := range files {
+		if file.IsDir() || IsRuleBaseTestFile(file.Name()) {
+			continue
+		}
+		dat, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			continue
+		}
+		if desc, err := parseRuleBaseDescription(dat); err == nil {
+			ids[desc.ID] = file.Name()
+		}
+	}
+	return ids, nil
+}
+
+// LintRuleBase checks the YAML source of a rulebase. It checks that
+//   - the meta id is set and is not the id of another rulebase in ids, which maps
+//     the ids of the other rulebases to their file names, except the file filename
+//   - every predicate used in the argument schemes and issue schemes is declared
+//     in the language, with the arity it is used with
+//   - every variable of the conclusions of an argument scheme occurs in its
+//     premises, assumptions or deletions, which is only a warning
+//   - the explanation predicates are declared with arity 1 or 2
+//   - the notDocConsentRequired and docConsentRequired statements are declared
+//     and concluded by some argument scheme
+//   - the rulebase can be compiled into a theory
+func LintRuleBase(src []byte, filename string, ids map[string]string) LintResult {
+	issues := []LintIssue{}
+	lines := schemeLines(src)
+	add := func(severity string, scheme string, format string, a ...interface{}) {
+		issues = append(issues, LintIssue{Severity: severity, Scheme: scheme, Line: lines[scheme], Message: fmt.Sprintf(format, a...)})
+	}
+	addErr := func(format string, err error) {
+		issues = append(issues, LintIssue{Severity: LintError, Line: errorLine(err), Message: fmt.Sprintf(format, err)})
+	}
+
+	rb := lintRuleBase{}
+	if err := yaml.Unmarshal(src, &rb); err != nil {
+		addErr("Could not parse the rulebase: %s", err)
+		return LintResult{false, issues}
+	}
+
+	if rb.Meta.ID == "" {
+		add(LintError, "", "The rulebase has no meta id")
+	} else if other, ok := ids[rb.Meta.ID]; ok && other != filename {
+		add(LintError, "", "The meta id %s is also the id of the rulebase %s", rb.Meta.ID, other)
+	}
+
+	// arities of the declared predicates
+	declared := make(map[string]map[int]bool)
+	langKeys := make([]string, 0, len(rb.Language))
+	for k := range rb.Language {
+		langKeys = append(langKeys, k)
+	}
+	sort.Strings(langKeys)
+	for _, k := range langKeys {
+		i := strings.LastIndex(k, "/")
+		var arity int
+		if i < 0 {
+			add(LintError, "", "The language declaration %s has no arity", k)
+			continue
+		}
+		if _, err := fmt.Sscanf(k[i+1:], "%d", &arity); err != nil {
+			add(LintError, "", "The language declaration %s has an invalid arity", k)
+			continue
+		}
+		if declared[k[:i]] == nil {
+			declared[k[:i]] = make(map[int]bool)
+		}
+		declared[k[:i]][arity] = true
+	}
+
+	for _, p := range rb.Meta.ExplanationPredicates {
+		if !declared[p.Predicate][1] && !declared[p.Predicate][2] {
+			add(LintError, "", "The explanation predicate %s is not declared in the language with arity 1 or 2", p.Predicate)
+		}
+	}
+
+	// checkTerm parses the term and checks that its predicate is declared,
+	// returning the variables of the term
+	checkTerm := func(scheme string, src string) []string {
+		t, ok := terms.ReadString(src)
+		if !ok {
+			add(LintError, scheme, "Could not parse %s", src)
+			return nil
+		}
+		if _, ok := t.(terms.Bool); ok {
+			// true, the conclusion of rules which only delete statements
+			return nil
+		}
+		pred, arity := lintPredicate(t)
+		if pred == "" {
+			add(LintError, scheme, "%s is not a statement", src)
+			return nil
+		}
+		if arities, ok := declared[pred]; !ok {
+			add(LintError, scheme, "The predicate %s of %s is not declared in the language", pred, src)
+		} else if !arities[arity] {
+			add(LintError, scheme, "The predicate %s is used with arity %d in %s, but declared with arity %s", pred, arity, src, lintArities(arities))
+		}
+		vars := []string{}
+		for _, v := range t.OccurVars() {
+			vars = append(vars, v.Name)
+		}
+		return vars
+	}
+
+	concluded := make(map[string]bool)
+	for _, s := range rb.Argument_schemes {
+		bound := make(map[string]bool)
+		for _, l := range [][]string{s.Premises, s.Assumptions, s.Deletions} {
+			for _, p := range l {
+				for _, v := range checkTerm(s.ID, p) {
+					bound[v] = true
+				}
+			}
+		}
+		for _, e := range s.Exceptions {
+			checkTerm(s.ID, e)
+		}
+		for _, c := range s.Conclusions {
+			unbound := []string{}
+			for _, v := range checkTerm(s.ID, c) {
+				if !bound[v] && v != "_" {
+					unbound = append(unbound, v)
+				}
+			}
+			if len(unbound) > 0 {
+				add(LintWarning, s.ID, "The variables %s of the conclusion %s do not occur in the premises", strings.Join(unbound, ", "), c)
+			}
+			if t, ok := terms.ReadString(c); ok {
+				if pred, _ := lintPredicate(t); pred != "" {
+					concluded[pred] = true
+				}
+			}
+		}
+	}
+
+	issueKeys := make([]string, 0, len(rb.Issue_schemes))
+	for k := range rb.Issue_schemes {
+		issueKeys = append(issueKeys, k)
+	}
+	sort.Strings(issueKeys)
+	for _, k := range issueKeys {
+		for _, p := range rb.Issue_schemes[k] {
+			checkTerm(k, p)
+		}
+	}
+
+	for _, m := range mandatoryStatements {
+		if !declared[m][0] {
+			add(LintError, "", "The mandatory statement %s is not declared in the language as %s/0", m, m)
+		} else if !concluded[m] {
+			add(LintError, "", "The mandatory statement %s is not the conclusion of any argument scheme", m)
+		}
+	}
+
+	if err := compileRuleBase(src); err != nil {
+		addErr("Could not compile the rulebase: %s", err)
+	}
+
+	valid := true
+	for _, i := range issues {
+		if i.Severity == LintError {
+			valid = false
+		}
+	}
+	return LintResult{valid, issues}
+}
+
+// lintPredicate returns the predicate and arity of a statement, or
+// an empty predicate if the term is not a statement
+func lintPredicate(t terms.Term) (string, int) {
+	switch t := t.(type) {
+	case terms.Atom:
+		return string(t), 0
+	case terms.Compound:
+		return t.Functor, len(t.Args)
+	}
+	return "", 0
+}
+
+func lintArities(arities map[int]bool) string {
+	l := []string{}
+	for a := range arities {
+		l = append(l, fmt.Sprint(a))
+	}
+	sort.Strings(l)
+	return strings.Join(l, " or ")
+}
+
+// compileRuleBase compiles the rulebase without caching the theory
+func compileRuleBase(src []byte) (err error) {
+	// the yaml decoder of carneades panics on some malformed rulebases
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	_, err = importRuleBase(bytes.NewReader(src))
+	return err
+}
+
+// LintRuleBase lints the YAML source of a rulebase, checking that its meta id is unique
+// among the loaded rulebases. If ruleBaseID is not empty, the source is a new version of
+// the rulebase with this id, which must be its meta id. See LintRuleBase.
+func (c *ComplianceCheckerPlugin) LintRuleBase(src []byte, ruleBaseID string) LintResult {
+	ids := make(map[string]string)
+	for id, rb := range c.RuleBaseDescriptions() {
+		ids[id] = rb.Filename
+	}
+	filename := ""
+	if ruleBaseID != "" {
+		filename = ids[ruleBaseID]
+	}
+	result := LintRuleBase(src, filename, ids)
+	if desc, err := parseRuleBaseDescription(src); err == nil && ruleBaseID != "" && desc.ID != ruleBaseID {
+		result.Valid = false
+		result.Issues = append(result.Issues, LintIssue{Severity: LintError, Message: fmt.Sprintf("The meta id %s does not match %s", desc.ID, ruleBaseID)})
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLintRuleBase(t *testing.T) {
+	valid := string(testRuleBaseWithID("test", true))
+	tests := []struct {
+		name         string
+		src          string
+		filename     string
+		ids          map[string]string
+		wantValid    bool
+		wantErrors   int
+		wantWarnings int
+	}{
+		{"valid", valid, "", nil, true, 0, 0},
+		{"valid, same file", valid, "test.yml", map[string]string{"test": "test.yml"}, true, 0, 0},
+		{"duplicate id", valid, "new.yml", map[string]string{"test": "test.yml"}, false, 1, 0},
+		{"no id", strings.Replace(valid, "id: test", "", 1), "", nil, false, 1, 0},
+		{"undeclared predicate", strings.Replace(valid, "consentRequired(S)", "consentNeeded(S)", 1), "", nil, false, 1, 0},
+		{"wrong arity", strings.Replace(valid, "consentRequired(S)", "consentRequired(S,S)", 1), "", nil, false, 1, 0},
+		{"missing declaration", strings.Replace(valid, "  docConsentRequired/0: docConsentRequired\n", "", 1), "", nil, false, 3, 0},
+		{"not concluded", strings.Replace(valid, "      - notDocConsentRequired\n", "      - docConsentRequired\n", 1), "", nil, false, 1, 0},
+		{"unbound variable", strings.Replace(valid, "consentRequired(dus(US,", "consentRequired(dus(X,", 1), "", nil, true, 0, 1},
+		{"malformed", "meta: [\n", "", nil, false, 1, 0},
+	}
+	for _, tt := range tests {
+		got := LintRuleBase([]byte(tt.src), tt.filename, tt.ids)
+		errors, warnings := 0, 0
+		for _, i := range got.Issues {
+			if i.Severity == LintError {
+				errors++
+			} else {
+				warnings++
+			}
+		}
+		if got.Valid != tt.wantValid || errors != tt.wantErrors || warnings != tt.wantWarnings {
+			t.Errorf("%q. LintRuleBase() = %v, want valid %v with %d errors and %d warnings", tt.name, got, tt.wantValid, tt.wantErrors, tt.wantWarnings)
+		}
+	}
+}
+
+func TestLintRuleBase_lines(t *testing.T) {
+	valid := string(testRuleBaseWithID("test", true))
+	tests := []struct {
+		name     string
+		src      string
+		wantLine int
+	}{
+		{"argument scheme", strings.Replace(valid, "consentRequired(S)", "consentNeeded(S)", 1), 43},
+		{"issue scheme", strings.Replace(valid, "[docConsentRequired,", "[docConsentNeeded,", 1), 16},
+		{"malformed", "meta:\n  id: test\nargument_schemes: [\n", 3},
+	}
+	for _, tt := range tests {
+		got := LintRuleBase([]byte(tt.src), "", nil)
+		if len(got.Issues) == 0 || got.Issues[0].Line != tt.wantLine {
+			t.Errorf("%q. LintRuleBase() = %v, want an issue in line %d", tt.name, got, tt.wantLine)
+		}
+	}
+}
+
+func TestComplianceCheckerPlugin_LintRuleBase(t *testing.T) {
+	c, dir := testPlugin(t)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name       string
+		src        []byte
+		ruleBaseID string
+		wantValid  bool
+	}{
+		{"new version", testRuleBaseWithID("test", false), "test", true},
+		{"new rulebase", testRuleBaseWithID("other", true), "", true},
+		{"existing id", testRuleBaseWithID("test", true), "", false},
+		{"mismatched id", testRuleBaseWithID("other", true), "test", false},
+	}
+	for _, tt := range tests {
+		if got := c.LintRuleBase(tt.src, tt.ruleBaseID); got.Valid != tt.wantValid {
+			t.Errorf("%q. ComplianceCheckerPlugin.LintRuleBase() = %v, want valid %v", tt.name, got, tt.wantValid)
+		}
+	}
+}
@@ -5,16 +5,15 @@
 package carneades
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
+	"sort"
+	"strconv"
 	"strings"
 
-	"path/filepath"
-
 	"github.com/Microsoft/DUCK/backend/ducklib/db"
 	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+	"github.com/Microsoft/DUCK/backend/ducklib/taxonomy"
 )
 
 //Normalizer is the main struct with all methods to normalize a struct into something carneades can work with
@@ -29,6 +28,9 @@ type Normalizer struct {
 	//codeDict     map[string]map[string]*structs.DictionaryEntry
 	//  [azure]-> DictionaryEntry
 	globalDict structs.Dictionary
+
+	assumptionSets []structs.AssumptionSet
+	taxonomyFacts  []string
 }
 
 //NormalizedDocument wraps structs.Document and adds an extra field 'Parts'.
@@ -43,11 +45,14 @@ type Normalizer struct {
 //IsA translates a custom code into a standard one
 //relationship is as follows: KEY is a VALUE
 //eg. ThingA is a capability, ThingB is a third_party_services
+//Facts are ground facts assumed when checking the document, e.g. those derived from the taxonomy
+//AssumptionSets are the assumption sets whose facts are assumed when checking the document
 type NormalizedDocument struct {
 	structs.Document
-	Statements []NormalizedStatement
-	IsA        map[string]string
-	Facts      []string
+	Statements     []NormalizedStatement
+	IsA            map[string]string
+	Facts          []string
+	AssumptionSets []structs.AssumptionSet
 }
 
 //NormalizedStatement wraps structs.Statement to add Location fields and a number field to identify it later
@@ -60,15 +65,10 @@ type NormalizedStatement struct {
 }
 
 //NewNormalizer returns a new initialized normalizer
-func NewNormalizer(doc structs.Document, db *db.Database, webdir string) (*Normalizer, error) {
-	//norm := Normalizer{original: doc, database: db}
-	norm := Normalizer{original: doc}
-
-	//put everything into new
-
+func NewNormalizer(doc structs.Document, db *db.Database, taxonomies *taxonomy.Service) (*Normalizer, error) {
 	user, err := db.GetUser(doc.Owner)
 	if err != nil {
-		return &norm, err
+		return &Normalizer{original: doc}, err
 	}
 
 	// set dictionary
@@ -81,23 +81,50 @@ func NewNormalizer(doc structs.Document, db *db.Database, webdir string) (*Norma
 	//	category : "2",
 	//	dictionaryType : "global"
 	//})
-	norm.globalDict = user.GlobalDictionary
-
-	//Taxonomy
-
-	docTaxPath := fmt.Sprintf("/assets/config/taxonomy-%s.json", doc.Locale)
-	docPath := filepath.Join(webdir, docTaxPath)
-	dat, err := ioutil.ReadFile(docPath)
+	norm, err := NewNormalizerWithDictionary(doc, user.GlobalDictionary, taxonomies)
 	if err != nil {
-		return nil, err
+		return norm, err
 	}
-	if err = json.Unmarshal(dat, &norm.docTaxonomy); err != nil {
-		return nil, err
+
+	// the assumption set of the document, or the default set of its owner
+	setID := doc.AssumptionSet
+	if setID == "" {
+		setID = user.AssumptionSet
 	}
+	if setID != "" {
+		set, err := db.GetAssumptionSet(setID)
+		if err != nil {
+			// documents created before assumption sets were stored reference
+			// the built-in default set of the frontend, which has no facts
+			log.Printf("Assumption set %s of document %s not found: %s", setID, doc.ID, err)
+		} else {
+			norm.SetAssumptionSets(set)
+		}
+	}
+	return norm, nil
+}
+
+//SetAssumptionSets sets the assumption sets whose facts are assumed when checking the document
+func (n *Normalizer) SetAssumptionSets(sets ...structs.AssumptionSet) {
+	n.assumptionSets = sets
+}
+
+//NewNormalizerWithDictionary returns a new initialized normalizer using the given global
+//dictionary instead of the one of the owner of the document. The taxonomy is that of the
+//locale of the document, or that of the default locale if there is none for its locale.
+func NewNormalizerWithDictionary(doc structs.Document, globalDict structs.Dictionary, taxonomies *taxonomy.Service) (*Normalizer, error) {
+	norm := Normalizer{original: doc, globalDict: globalDict, taxonomyFacts: taxonomies.Facts()}
+	norm.docTaxonomy, _ = taxonomies.Get(doc.Locale)
 	return &norm, nil
 }
 
-//GetNormalized returns a normalized document carneades can work with
+//Taxonomy returns the taxonomy of the locale of the document
+func (n *Normalizer) Taxonomy() structs.Taxonomy {
+	return n.docTaxonomy
+}
+
+//GetNormalized returns a normalized document carneades can work with. If statements of the
+//document cannot be normalized, the error is structs.ValidationErrors listing all of their problems.
 func (n *Normalizer) GetNormalized() (*NormalizedDocument, error) {
 
 	n.normalized = new(NormalizedDocument)
@@ -107,6 +134,11 @@ func (n *Normalizer) GetNormalized() (*NormalizedDocument, error) {
 	n.normalized.Name = n.original.Name
 	n.normalized.Owner = n.original.Owner
 	n.normalized.Revision = n.original.Revision
+	n.normalized.AssumptionSet = n.original.AssumptionSet
+	n.normalized.AssumptionSets = n.assumptionSets
+	// the facts of the taxonomy, e.g. kindOf(market_contextual,market), so that
+	// rulebases do not have to repeat the hierarchies of the taxonomy
+	n.normalized.Facts = append([]string{}, n.taxonomyFacts...)
 
 	//creates dict and moves statements into norm dict
 	if err := n.createDict(); err != nil {
@@ -124,97 +156,164 @@ func (n *Normalizer) GetNormalized() (*NormalizedDocument, error) {
 	return n.normalized, nil
 }
 
-type snippet struct {
-	Name      string
-	Something string
-}
-
-//Unfold moves the except and and clauses int their own statements
+//unfold moves the and and except clauses of the statements into their own statements.
+//The data category of a statement and those of its and clauses are included, the ones of
+//its except clauses and of the and clauses following an except are excluded. An included
+//category containing an excluded one, at any depth of the taxonomy, is replaced by its
+//subcategories until the excluded one is left out, e.g. customer content except health data
+//becomes customer content credentials, contact, genetic, ... The sub-statements are numbered
+//in the order of the clauses and of the categories of the taxonomy with PlaceInStruct
+//and the suffix -<PlaceInStruct> of their tracking ids.
 func (n *Normalizer) unfold() error {
-	normalized := make([]NormalizedStatement, 0)
-
-	for h, stmt := range n.normalized.Statements {
+	normalized := make([]NormalizedStatement, 0, len(n.normalized.Statements))
 
+	for _, stmt := range n.normalized.Statements {
 		stmt.PlaceInStruct = 0
-		except := false
-		if stmt.DataCategories == nil || len(stmt.DataCategories) == 0 {
+		if len(stmt.DataCategories) == 0 {
 			normalized = append(normalized, stmt)
+			continue
 		}
-		for i, dcat := range stmt.DataCategories {
-
-			//if except, the first one or the one after this one
-			if dcat.Op == structs.EXCEPT || (len(stmt.DataCategories)-1 > i && stmt.DataCategories[i+1].Op == structs.EXCEPT) {
-
-				code := dcat.DataCategoryCode
-				if dcat.Op == structs.EXCEPT {
-					code = n.normalized.Statements[h].DataCategoryCode
-				}
-				cats := n.getCategories(code)
-				except = true
-
-				for j := i; j < len(stmt.DataCategories); j++ {
-					cats[stmt.DataCategories[j].DataCategoryCode] = nil
-				}
+		for k, dcat := range n.expandCategories(stmt.Statement) {
+			statement := createFromStatement(stmt, dcat.DataCategoryCode, dcat.QualifierCode)
+			statement.PlaceInStruct = k
+			statement.TrackingID = statement.TrackingID + "-" + fmt.Sprint(k)
+			normalized = append(normalized, statement)
+		}
+	}
+	n.normalized.Statements = normalized
+	return nil
+}
 
-				k := i
-				for cat, snip := range cats {
-					if snip != nil {
-						statement := createFromStatement(stmt, cat, dcat.QualifierCode)
-						statement.PlaceInStruct = k
-						statement.TrackingID = statement.TrackingID + "-" + fmt.Sprint(k)
-						statement.DataCategories = nil
-						normalized = append(normalized, statement)
-						k++
-					}
-				}
+//clause is an and clause of a statement, numbered 0 for the data category of the
+//statement and i for the i-th of its DataCategories
+type clause struct {
+	structs.DataCategories
+	Index int
+}
 
-				break
-			}
+//splitClauses returns the included clauses of a statement, starting with its data category,
+//and the excluded data categories, see unfold. A clause without qualifier is unqualified.
+func splitClauses(stmt structs.Statement) ([]clause, []string) {
+	included := []clause{{structs.DataCategories{Op: structs.AND, QualifierCode: stmt.QualifierCode, DataCategoryCode: stmt.DataCategoryCode}, 0}}
+	excluded := []string{}
+	except := false
+	for i, dcat := range stmt.DataCategories {
+		if dcat.Op == structs.EXCEPT {
+			except = true
+		}
+		if except {
+			excluded = append(excluded, dcat.DataCategoryCode)
+			continue
+		}
+		if dcat.QualifierCode == "" {
+			dcat.QualifierCode = "unqualified"
+		}
+		included = append(included, clause{dcat, i + 1})
+	}
+	return included, excluded
+}
 
-			// handling and when
-			// - either this is an AND and the next one is not an EXCEPT
-			// - or this one is the last one and an AND
-			// - only if we did not yet have had an EXCEPT
-
-			if ((len(stmt.DataCategories)-1 > i && dcat.Op == structs.AND && stmt.DataCategories[i+1].Op != structs.EXCEPT) || (len(stmt.DataCategories)-1 == i && dcat.Op == structs.AND)) && !except {
-				//log.Println("in 2")
-				//log.Printf("dcat: %#+v\n", dcat)
-				statement := createFromStatement(stmt, dcat.DataCategoryCode, dcat.QualifierCode)
-				statement.PlaceInStruct = i
-				statement.TrackingID = statement.TrackingID + "-" + fmt.Sprint(i)
-				statement.DataCategories = nil
-				normalized = append(normalized, statement)
+//expandCategories returns the included data categories of a statement with and/except
+//clauses, without the excluded ones, each with the clause it stems from, see unfold
+func (n *Normalizer) expandCategories(stmt structs.Statement) []clause {
+	included, excluded := splitClauses(stmt)
+	expanded := []clause{}
+	seen := make(map[structs.DataCategories]bool)
+	for _, inc := range included {
+		for _, code := range n.excludeCategories(inc.DataCategoryCode, excluded) {
+			dcat := structs.DataCategories{Op: structs.AND, QualifierCode: inc.QualifierCode, DataCategoryCode: code}
+			if !seen[dcat] {
+				seen[dcat] = true
+				expanded = append(expanded, clause{dcat, inc.Index})
 			}
-
 		}
 	}
-	//log.Printf("n: %v", normalized)
-	n.normalized.Statements = normalized
-	return nil
+	return expanded
 }
 
-func (n *Normalizer) getCategories(code string) map[string]*snippet {
-	cat := make(map[string]*snippet, 0)
+//excludeCategories returns the data categories covering the category of code except the
+//excluded ones: the code itself if it contains none of them, nothing if it is contained in
+//one of them and otherwise the codes left of its subcategories, in the order of the taxonomy
+func (n *Normalizer) excludeCategories(code string, excluded []string) []string {
+	category := n.getDataCategory(code)
+	containsExcluded := false
+	for _, ex := range excluded {
+		exCategory := n.getDataCategory(ex)
+		if ex == code || (category != "" && exCategory != "" && subCategory(category, exCategory)) {
+			return nil
+		}
+		if category != "" && exCategory != "" && exCategory != category && subCategory(exCategory, category) {
+			containsExcluded = true
+		}
+	}
+	if !containsExcluded {
+		return []string{code}
+	}
 
-	tax := n.docTaxonomy["dataCategory"]
-	filterCat := ""
-	filterCatLength := 0
+	// an excluded custom code below a category without subcategories cannot be left out
+	subs := n.getSubCategories(category)
+	if len(subs) == 0 {
+		return []string{code}
+	}
+	codes := []string{}
+	for _, child := range subs {
+		codes = append(codes, n.excludeCategories(child.Code, excluded)...)
+	}
+	return codes
+}
 
-	for _, c := range tax {
+//getDataCategory returns the category of a data category code in the taxonomy, e.g. 1.3,
+//or that of the dictionary entry of a custom code, or "" if the code is unknown
+func (n *Normalizer) getDataCategory(code string) string {
+	for _, c := range n.docTaxonomy["dataCategory"] {
 		if c.Code == code {
-			filterCat = c.Category
-			filterCatLength = len(strings.Split(filterCat, "."))
+			return c.Category
 		}
 	}
+	if dict, prs := n.original.Dictionary[code]; prs {
+		return dict.Category
+	}
+	if dict, prs := n.globalDict[code]; prs {
+		return dict.Category
+	}
+	return ""
+}
 
-	for _, c := range tax {
-		if strings.HasPrefix(c.Category, filterCat) && len(strings.Split(c.Category, ".")) == filterCatLength+1 {
-			cat[c.Code] = &snippet{c.Code, c.Category}
-
+//getSubCategories returns the entries of the data categories directly below the category
+//in the taxonomy, sorted by category, e.g. 2.1.2 before 2.1.10
+func (n *Normalizer) getSubCategories(category string) []structs.TaxonomyEntry {
+	subs := []structs.TaxonomyEntry{}
+	for _, c := range n.docTaxonomy["dataCategory"] {
+		if i := strings.LastIndex(c.Category, "."); i > 0 && c.Category[:i] == category {
+			subs = append(subs, c)
 		}
 	}
+	sort.Slice(subs, func(i, j int) bool {
+		return lessCategory(subs[i].Category, subs[j].Category)
+	})
+	return subs
+}
 
-	return cat
+//subCategory reports whether the category is the parent category or below it, e.g. 1.3.2 of 1
+func subCategory(category, parent string) bool {
+	return category == parent || strings.HasPrefix(category, parent+".")
+}
+
+//lessCategory orders categories by their numbers, e.g. 2.1.2 before 2.1.10 before 2.2
+func lessCategory(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		ai, aerr := strconv.Atoi(as[i])
+		bi, berr := strconv.Atoi(bs[i])
+		if aerr == nil && berr == nil {
+			return ai < bi
+		}
+		return as[i] < bs[i]
+	}
+	return len(as) < len(bs)
 }
 
 func createFromStatement(stmt NormalizedStatement, DataCategoryCode string, QualifierCode string) NormalizedStatement {
@@ -252,56 +351,57 @@ func (n *Normalizer) setLocation() error {
 	return nil
 }
 
-//createDict normalizes a Document for further validation
+//createDict normalizes a Document for further validation. It checks every statement and
+//returns all problems found as structs.ValidationErrors, not only the first one.
 func (n *Normalizer) createDict() error {
 
 	//make sure we have every part only once for each code
 	//for this we make a map for every code which we will later transform into a list
 	isA := make(map[string]string)
+	var errs structs.ValidationErrors
 
 	// we check if we have missing fields in a Statements
 	//if not we get original taxonomy code for each field in each statement
 	//and save it into parts map
-	for _, statement := range n.original.Statements {
+	for i, statement := range n.original.Statements {
 		normstmt := NormalizedStatement{}
+		invalid := func(field, code, msg string) {
+			errs = append(errs, structs.ValidationError{TrackingID: statement.TrackingID, Statement: i, Field: field, Code: code, Message: msg})
+		}
+		//a custom code has to be the same standard code in all statements
+		addIsA := func(field, code, returnCode string) {
+			if prev, prs := isA[code]; !prs {
+				isA[code] = returnCode
+			} else if prev != returnCode {
+				invalid(field, structs.ValidationAmbiguousCode,
+					fmt.Sprintf("the custom code %s is %s here but %s in another statement", code, returnCode, prev))
+			}
+		}
 
 		//if data use category, data category or all scopes are missing, we cant work with this statement
 		if statement.UseScopeCode == "" && statement.ResultScopeCode == "" && statement.SourceScopeCode == "" {
-			return fmt.Errorf("statement is missing all scope fields: %s", statement.TrackingID)
+			invalid("useScopeCode", structs.ValidationMissingScope, "statement is missing all scope fields, one of useScopeCode, sourceScopeCode and resultScopeCode is required")
 		}
 		if statement.ActionCode == "" {
-			return fmt.Errorf("statement is missing data use field: %s", statement.TrackingID)
+			invalid("actionCode", structs.ValidationMissingField, "statement is missing data use field")
 		}
 		if statement.DataCategoryCode == "" {
-			return fmt.Errorf("statement is missing data category field: %s", statement.TrackingID)
+			invalid("dataCategoryCode", structs.ValidationMissingField, "statement is missing data category field")
 		}
 
 		//find original code for the one used
-		if returnCode := n.getCode("dataUseCategory", statement.DataCategoryCode); returnCode != "" {
-			if _, prs := isA[statement.DataCategoryCode]; prs == false {
-				isA[statement.DataCategoryCode] = returnCode
-			} else if prs == true && isA[statement.DataCategoryCode] != returnCode {
-				return fmt.Errorf("The following custom code can be two or more things, which should not be possible: %s", statement.DataCategoryCode)
-			}
-
+		if returnCode := n.getCode("dataCategory", statement.DataCategoryCode); returnCode != "" {
+			addIsA("dataCategoryCode", statement.DataCategoryCode, returnCode)
 		}
 		if returnCode := n.getCode("scope", statement.UseScopeCode); returnCode != "" {
-			if _, prs := isA[statement.UseScopeCode]; prs == false {
-				isA[statement.UseScopeCode] = returnCode
-			} else if prs == true && isA[statement.UseScopeCode] != returnCode {
-				return fmt.Errorf("The following custom code can be two or more things, which should not be possible: %s", statement.UseScopeCode)
-			}
+			addIsA("useScopeCode", statement.UseScopeCode, returnCode)
 			normstmt.UseScopeLocation = n.getLocationFromCode(statement.UseScopeCode)
 			if normstmt.UseScopeLocation != "" {
 				log.Printf("UseScopeLocation: %#v", normstmt.UseScopeLocation)
 			}
 		}
 		if returnCode := n.getCode("scope", statement.ResultScopeCode); returnCode != "" {
-			if _, prs := isA[statement.ResultScopeCode]; prs == false {
-				isA[statement.ResultScopeCode] = returnCode
-			} else if prs == true && isA[statement.ResultScopeCode] != returnCode {
-				return fmt.Errorf("The following custom code can be two or more things, which should not be possible: %s", statement.ResultScopeCode)
-			}
+			addIsA("resultScopeCode", statement.ResultScopeCode, returnCode)
 			normstmt.ResultScopeLocation = n.getLocationFromCode(statement.ResultScopeCode)
 			if normstmt.ResultScopeLocation != "" {
 				log.Printf("ResultScopeLocation: %#v", normstmt.ResultScopeLocation)
@@ -309,50 +409,15 @@ func (n *Normalizer) createDict() error {
 		}
 
 		if returnCode := n.getCode("scope", statement.SourceScopeCode); returnCode != "" {
-			if _, prs := isA[statement.SourceScopeCode]; prs == false {
-				isA[statement.SourceScopeCode] = returnCode
-			} else if prs == true && isA[statement.SourceScopeCode] != returnCode {
-				return fmt.Errorf("The following custom code can be two or more things, which should not be possible: %s", statement.SourceScopeCode)
-			}
+			addIsA("sourceScopeCode", statement.SourceScopeCode, returnCode)
 			normstmt.SourceScopeLocation = n.getLocationFromCode(statement.SourceScopeCode)
 			if normstmt.SourceScopeLocation != "" {
 				log.Printf("UseScopeLocation: %#v", normstmt.SourceScopeLocation)
 			}
 		}
 
-		// if qualifier is missing that means the qualifier is "unqualified"
-		if statement.QualifierCode == "" {
-			statement.QualifierCode = "unqualified"
-		}
-
-		//if we have at least one scope we can fill the other two (19944  10.2.2.1)
-
-		if statement.UseScopeCode != "" {
-			if statement.SourceScopeCode == "" {
-				statement.SourceScopeCode = statement.UseScopeCode
-			}
-			if statement.ResultScopeCode == "" {
-				statement.ResultScopeCode = statement.UseScopeCode
-			}
-		}
+		statement = fillDefaults(statement)
 
-		if statement.SourceScopeCode != "" {
-			if statement.UseScopeCode == "" {
-
-				statement.UseScopeCode = statement.SourceScopeCode
-			}
-			if statement.ResultScopeCode == "" {
-				statement.ResultScopeCode = statement.SourceScopeCode
-			}
-		}
-		if statement.ResultScopeCode != "" {
-			if statement.UseScopeCode == "" {
-				statement.UseScopeCode = statement.ResultScopeCode
-			}
-			if statement.SourceScopeCode == "" {
-				statement.SourceScopeCode = statement.ResultScopeCode
-			}
-		}
 		//add statement to normalized Document
 
 		normstmt.ActionCode = statement.ActionCode
@@ -370,7 +435,12 @@ func (n *Normalizer) createDict() error {
 		n.normalized.Statements = append(n.normalized.Statements, normstmt)
 
 	}
-	fmt.Println(isA)
+	if len(errs) > 0 {
+		return errs
+	}
+	if DEBUG {
+		log.Printf("isA: %v", isA)
+	}
 	n.normalized.IsA = isA
 	//write partsOf and isA map into Facts
 	//n.getFacts() -> while we only have isa, this happens in compliance.go
@@ -379,11 +449,50 @@ func (n *Normalizer) createDict() error {
 	return nil
 }
 
+//fillDefaults returns the statement with the fields the author may omit filled in:
+//a missing qualifier is "unqualified" and missing scopes are the given ones
+func fillDefaults(statement structs.Statement) structs.Statement {
+	// if qualifier is missing that means the qualifier is "unqualified"
+	if statement.QualifierCode == "" {
+		statement.QualifierCode = "unqualified"
+	}
+
+	//if we have at least one scope we can fill the other two (19944  10.2.2.1)
+
+	if statement.UseScopeCode != "" {
+		if statement.SourceScopeCode == "" {
+			statement.SourceScopeCode = statement.UseScopeCode
+		}
+		if statement.ResultScopeCode == "" {
+			statement.ResultScopeCode = statement.UseScopeCode
+		}
+	}
+
+	if statement.SourceScopeCode != "" {
+		if statement.UseScopeCode == "" {
+
+			statement.UseScopeCode = statement.SourceScopeCode
+		}
+		if statement.ResultScopeCode == "" {
+			statement.ResultScopeCode = statement.SourceScopeCode
+		}
+	}
+	if statement.ResultScopeCode != "" {
+		if statement.UseScopeCode == "" {
+			statement.UseScopeCode = statement.ResultScopeCode
+		}
+		if statement.SourceScopeCode == "" {
+			statement.SourceScopeCode = statement.ResultScopeCode
+		}
+	}
+	return statement
+}
+
 //getFacts transforms the IsA and Parts maps into a list of CHR facts
 //in the form of "IsA(Thing, capability)." and "PartOf(Thing, OtherThing)."
 func (n *Normalizer) getFacts() {
 	for k, v := range n.normalized.IsA {
-		n.normalized.Facts = append(n.normalized.Facts, fmt.Sprintf("isA(%s,%s).", k, v))
+		n.normalized.Facts = append(n.normalized.Facts, isATerm(k, v).String()+".")
 	}
 	//TODO: also add parts to this list: partOf(A,B)
 
@@ -466,61 +575,41 @@ func FoldExplanation(Exp Explanation) Explanation {
 		if _, ok := NewExp[id]; !ok {
 			NewExp[id] = stmtexp
 		} else {
-
-			cr := BoolValue{
-				Assumed: NewExp[id].ConsentRequired.Assumed && stmtexp.ConsentRequired.Assumed,
-				Value:   NewExp[id].ConsentRequired.Value || stmtexp.ConsentRequired.Value,
-			}
-			pii := BoolValue{
-				Assumed: NewExp[id].Pii.Assumed && stmtexp.Pii.Assumed,
-				Value:   NewExp[id].Pii.Value || stmtexp.Pii.Value,
-			}
-			li := BoolValue{
-				Assumed: NewExp[id].Li.Assumed && stmtexp.Li.Assumed,
-				Value:   NewExp[id].Li.Value || stmtexp.Li.Value,
+			newstmtexp := make(StmtExplanation, len(stmtexp))
+			for p, v := range NewExp[id] {
+				newstmtexp[p] = v
 			}
-
-			inr := BoolValue{
-				Assumed: NewExp[id].IDNotRequired.Assumed && stmtexp.IDNotRequired.Assumed,
-				Value:   NewExp[id].IDNotRequired.Value || stmtexp.IDNotRequired.Value,
-			}
-			tpii := BoolValue{
-				Assumed: NewExp[id].TransferPii.Assumed && stmtexp.TransferPii.Assumed,
-				Value:   NewExp[id].TransferPii.Value || stmtexp.TransferPii.Value,
-			}
-			cr2tpii := BoolValue{
-				Assumed: NewExp[id].ConsentRequired2TransferPii.Assumed && stmtexp.ConsentRequired2TransferPii.Assumed,
-				Value:   NewExp[id].ConsentRequired2TransferPii.Value || stmtexp.ConsentRequired2TransferPii.Value,
-			}
-
-			cpTemp := NewExp[id].CompatiblePurpose
-			cpTemp = append(cpTemp, stmtexp.CompatiblePurpose...)
-			cp := make([]string, 0)
-			for _, cid := range cpTemp {
-				if strings.Split(cid, "-")[0] != id {
-					cp = append(cp, strings.Split(cid, "-")[0])
+			for p, v := range stmtexp {
+				if old, ok := newstmtexp[p]; ok {
+					newstmtexp[p] = foldBoolValue(id, old, v)
+				} else {
+					newstmtexp[p] = v
 				}
-
-			}
-
-			newstmtexp := StmtExplanation{
-				ConsentRequired:             cr,
-				Pii:                         pii,
-				Li:                          li,
-				CompatiblePurpose:           cp,
-				IDNotRequired:               inr,
-				TransferPii:                 tpii,
-				ConsentRequired2TransferPii: cr2tpii,
 			}
-
 			NewExp[id] = newstmtexp
 		}
 	}
 	return NewExp
 }
 
-//Denormalize denormalizes a Document after validation
-func (n *Normalizer) Denormalize() *structs.Document {
-	// we have the original
-	return &n.original
+//foldBoolValue folds the explanations of a predicate for two clauses of the statement id.
+//The predicate applies if it applies to any clause, with the arguments of both clauses.
+//The clauses of the statement are removed from the related statements.
+func foldBoolValue(id string, a BoolValue, b BoolValue) BoolValue {
+	v := BoolValue{
+		Assumed:        a.Assumed && b.Assumed,
+		Value:          a.Value || b.Value,
+		Arguments:      append(append([]ArgumentExplanation{}, a.Arguments...), b.Arguments...),
+		AssumptionSets: mergeStrings(a.AssumptionSets, b.AssumptionSets),
+	}
+	if a.Statements != nil || b.Statements != nil {
+		v.Statements = make([]string, 0)
+		for _, sid := range append(append([]string{}, a.Statements...), b.Statements...) {
+			if strings.Split(sid, "-")[0] != id {
+				v.Statements = append(v.Statements, strings.Split(sid, "-")[0])
+			}
+		}
+	}
+	return v
 }
+
@@ -4,18 +4,21 @@
 package carneades
 
 import (
+	"fmt"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	"github.com/Microsoft/DUCK/backend/ducklib/db"
 	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+	"github.com/Microsoft/DUCK/backend/ducklib/taxonomy"
 )
 
 func TestNewNormalizer(t *testing.T) {
 	type args struct {
-		doc    structs.Document
-		db     *db.Database
-		webdir string
+		doc        structs.Document
+		db         *db.Database
+		taxonomies *taxonomy.Service
 	}
 	tests := []struct {
 		name    string
@@ -27,7 +30,7 @@ func TestNewNormalizer(t *testing.T) {
 	}
 	t.Errorf("Implement Normalize tests")
 	for _, tt := range tests {
-		got, err := NewNormalizer(tt.args.doc, tt.args.db, tt.args.webdir)
+		got, err := NewNormalizer(tt.args.doc, tt.args.db, tt.args.taxonomies)
 		if (err != nil) != tt.wantErr {
 			t.Errorf("%q. NewNormalizer() error = %v, wantErr %v", tt.name, err, tt.wantErr)
 			continue
@@ -79,17 +82,178 @@ func Test_normalizer_getCode(t *testing.T) {
 	}
 }
 
-func Test_normalizer_Denormalize(t *testing.T) {
+func TestNormalizer_GetNormalized_validationErrors(t *testing.T) {
+	tax, err := taxonomy.Load(filepath.Join("..", "..", "..", "frontend", "src", "assets", "config"), taxonomy.DefaultLocale)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	stmt := func(id, scope, action, category string) structs.Statement {
+		return structs.Statement{UseScopeCode: scope, ActionCode: action, DataCategoryCode: category, TrackingID: id}
+	}
+	doc := structs.Document{
+		Statements: []structs.Statement{
+			stmt("s0", "my_cloud", "provide", "customer_content"),
+			stmt("s1", "capability", "", "customer_content"),
+			stmt("s2", "", "provide", ""),
+			stmt("s3", "capability", "provide", "my_cloud"),
+		},
+		// the category 1 of a custom code is the scope capability in s0 but the
+		// data category customer_content in s3
+		Dictionary: structs.Dictionary{"my_cloud": {Value: "My Cloud", Code: "my_cloud", Category: "1"}},
+	}
+	n, err := NewNormalizerWithDictionary(doc, nil, tax)
+	if err != nil {
+		t.Fatalf("NewNormalizerWithDictionary() error = %v", err)
+	}
+	_, err = n.GetNormalized()
+	got, ok := err.(structs.ValidationErrors)
+	if !ok {
+		t.Fatalf("GetNormalized() error = %v, want validation errors", err)
+	}
+	type problem struct {
+		TrackingID string
+		Statement  int
+		Field      string
+		Code       string
+	}
+	want := []problem{
+		{"s1", 1, "actionCode", structs.ValidationMissingField},
+		{"s2", 2, "useScopeCode", structs.ValidationMissingScope},
+		{"s2", 2, "dataCategoryCode", structs.ValidationMissingField},
+		{"s3", 3, "dataCategoryCode", structs.ValidationAmbiguousCode},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("GetNormalized() errors = %v, want %v", got, want)
+	}
+	for i, e := range got {
+		if p := (problem{e.TrackingID, e.Statement, e.Field, e.Code}); p != want[i] || e.Message == "" {
+			t.Errorf("GetNormalized() error %v = %+v, want %+v", i, e, want[i])
+		}
+	}
+	if msg := "the custom code my_cloud is customer_content here but capability in another statement"; got[3].Message != msg {
+		t.Errorf("GetNormalized() error 3 message = %q, want %q", got[3].Message, msg)
+	}
+}
+
+func TestNormalizer_unfold(t *testing.T) {
+	tax, err := taxonomy.Load(filepath.Join("..", "..", "..", "frontend", "src", "assets", "config"), taxonomy.DefaultLocale)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	and := func(code string) structs.DataCategories {
+		return structs.DataCategories{Op: structs.AND, QualifierCode: "identified_data", DataCategoryCode: code}
+	}
+	except := func(code string) structs.DataCategories {
+		return structs.DataCategories{Op: structs.EXCEPT, DataCategoryCode: code}
+	}
 	tests := []struct {
-		name string
-		n    *Normalizer
-		want *structs.Document
+		name     string
+		category string
+		clauses  []structs.DataCategories
+		dict     structs.Dictionary
+		want     []string // tracking id, place in struct, qualifier and data category of the sub-statements
 	}{
-		// TODO: Add test cases.
+		{"no clauses", "customer_content", nil, nil, []string{
+			"s0 0 unqualified customer_content",
+		}},
+		{"and", "customer_content", []structs.DataCategories{and("account_data")}, nil, []string{
+			"s0-0 0 unqualified customer_content",
+			"s0-1 1 identified_data account_data",
+		}},
+		{"and without qualifier", "customer_content", []structs.DataCategories{{DataCategoryCode: "account_data"}}, nil, []string{
+			"s0-0 0 unqualified customer_content",
+			"s0-1 1 unqualified account_data",
+		}},
+		{"and twice", "customer_content", []structs.DataCategories{and("account_data"), and("account_data")}, nil, []string{
+			"s0-0 0 unqualified customer_content",
+			"s0-1 1 identified_data account_data",
+		}},
+		{"except", "customer_content", []structs.DataCategories{except("customer_content_health")}, nil, []string{
+			"s0-0 0 unqualified customer_content_credentials",
+			"s0-1 1 unqualified customer_content_contact",
+			"s0-2 2 unqualified customer_content_genetic",
+			"s0-3 3 unqualified customer_content_biometric",
+			"s0-4 4 unqualified customer_content_children",
+			"s0-5 5 unqualified customer_content_opinions",
+			"s0-6 6 unqualified customer_content_financial",
+		}},
+		{"except two levels down", "derived_data", []structs.DataCategories{except("derived_data_user_location")}, nil, []string{
+			"s0-0 0 unqualified derived_data_user_telemetry",
+			"s0-1 1 unqualified derived_data_user_connectivity",
+			"s0-2 2 unqualified derived_data_user_usage",
+			"s0-3 3 unqualified derived_data_user_demographic",
+			"s0-4 4 unqualified derived_data_user_profiling",
+			"s0-5 5 unqualified derived_data_user_content",
+			"s0-6 6 unqualified derived_data_user_browsing",
+			"s0-7 7 unqualified derived_data_user_search",
+			"s0-8 8 unqualified derived_data_user_social",
+			"s0-9 9 unqualified derived_data_user_biometric",
+			"s0-10 10 unqualified derived_data_user_contact",
+			"s0-11 11 unqualified derived_data_user_environmental",
+			"s0-12 12 unqualified derived_data_organization",
+		}},
+		{"and except and", "customer_content", []structs.DataCategories{
+			and("derived_data"), except("customer_content_health"), and("derived_data_user"), and("customer_content_financial"),
+		}, nil, []string{
+			"s0-0 0 unqualified customer_content_credentials",
+			"s0-1 1 unqualified customer_content_contact",
+			"s0-2 2 unqualified customer_content_genetic",
+			"s0-3 3 unqualified customer_content_biometric",
+			"s0-4 4 unqualified customer_content_children",
+			"s0-5 5 unqualified customer_content_opinions",
+			"s0-6 6 identified_data derived_data_organization",
+		}},
+		{"except of another category", "account_data", []structs.DataCategories{except("customer_content_health")}, nil, []string{
+			"s0-0 0 unqualified account_data",
+		}},
+		{"except everything", "provider_data", []structs.DataCategories{except("provider_data")}, nil, nil},
+		{"except a custom code", "account_data", []structs.DataCategories{except("cards")},
+			structs.Dictionary{"cards": {Code: "cards", Category: "4.2"}}, []string{
+				"s0-0 0 unqualified account_data_customer",
+			}},
+	}
+	for _, tt := range tests {
+		doc := structs.Document{
+			Statements: []structs.Statement{{
+				UseScopeCode: "capability", ActionCode: "provide", DataCategoryCode: tt.category,
+				DataCategories: tt.clauses, TrackingID: "s0",
+			}},
+			Dictionary: tt.dict,
+		}
+		// the expansion must not depend on the order of maps
+		for i := 0; i < 10; i++ {
+			n, _ := NewNormalizerWithDictionary(doc, nil, tax)
+			normDoc, err := n.GetNormalized()
+			if err != nil {
+				t.Fatalf("%q. GetNormalized() error = %v", tt.name, err)
+			}
+			var got []string
+			for _, s := range normDoc.Statements {
+				got = append(got, fmt.Sprintf("%s %d %s %s", s.TrackingID, s.PlaceInStruct, s.QualifierCode, s.DataCategoryCode))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%q. GetNormalized() statements = %q, want %q", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func Test_lessCategory(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1", "2", true},
+		{"2.1.2", "2.1.10", true},
+		{"2.1.10", "2.2", true},
+		{"2", "2.1", true},
+		{"2.1", "2", false},
+		{"10", "9", false},
 	}
 	for _, tt := range tests {
-		if got := tt.n.Denormalize(); !reflect.DeepEqual(got, tt.want) {
-			t.Errorf("%q. normalizer.Denormalize() = %v, want %v", tt.name, got, tt.want)
+		if got := lessCategory(tt.a, tt.b); got != tt.want {
+			t.Errorf("lessCategory(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
 		}
 	}
 }
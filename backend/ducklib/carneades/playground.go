@@ -0,0 +1,96 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"bytes"
+	"time"
+)
+
+// PlaygroundResult is the result of checking a document against an unsaved rulebase.
+// If the rulebase has errors, only the lint result is set.
+type PlaygroundResult struct {
+	RuleBaseID  string               `json:"rulebaseId,omitempty"`
+	Lint        LintResult           `json:"lint"` // the errors and warnings of the rulebase, with line numbers
+	Compliant   Verdict              `json:"compliant,omitempty"`
+	Explanation Explanation          `json:"explanation,omitempty"` // folded, as returned when checking a document
+	Issues      []UnresolvedIssue    `json:"issues,omitempty"`      // the unresolved issues of an undecided verdict
+	Statistics  *InferenceStatistics `json:"statistics,omitempty"`
+	Graph       string               `json:"graph,omitempty"` // the argument graph, if a graph format was requested
+}
+
+// InferenceStatistics describes the size of an argument graph and the time
+// needed to compile the rulebase and to construct and label the graph
+type InferenceStatistics struct {
+	Statements      int     `json:"statements"`
+	Arguments       int     `json:"arguments"`
+	Issues          int     `json:"issues"`
+	Assumptions     int     `json:"assumptions"`
+	CompileMillis   float64 `json:"compileMillis"`
+	InferenceMillis float64 `json:"inferenceMillis"`
+}
+
+// Playground lints and compiles the YAML source of a rulebase, which is neither saved
+// nor cached, and checks the document against it. The rulebase may have the id of a
+// loaded rulebase, which is not replaced. If graphFormat is not empty, the argument
+// graph is exported in this format, see ExportGraph. An error is returned if the
+// rulebase is valid but the document could not be checked.
+func (c *ComplianceCheckerPlugin) Playground(src []byte, document *NormalizedDocument, graphFormat string) (*PlaygroundResult, error) {
+	result := &PlaygroundResult{Lint: LintRuleBase(src, "", nil)}
+	if !result.Lint.Valid {
+		return result, nil
+	}
+	desc, err := parseRuleBaseDescription(src)
+	if err != nil {
+		return nil, err
+	}
+	result.RuleBaseID = desc.ID
+
+	// the theory is cached by the playground checker only
+	checker := MakeComplianceChecker()
+	checker.Search = c.checker.Search
+	start := time.Now()
+	theory, err := compileTheory(checker, desc, src)
+	if err != nil {
+		return nil, err
+	}
+	compiled := time.Now()
+	ag, err := checker.ArgumentGraph(theory, document)
+	if err != nil {
+		return nil, err
+	}
+	stats := &InferenceStatistics{
+		Statements:      len(ag.Statements),
+		Arguments:       len(ag.Arguments),
+		Issues:          len(ag.Issues),
+		Assumptions:     len(ag.Assumptions),
+		CompileMillis:   millis(compiled.Sub(start)),
+		InferenceMillis: millis(time.Since(compiled)),
+	}
+	result.Statistics = stats
+
+	v, issues, err := verdict(ag)
+	if err != nil {
+		return nil, err
+	}
+	e, err := checker.GetExplanation(theory, ag)
+	if err != nil {
+		return nil, err
+	}
+	result.Compliant, result.Issues, result.Explanation = v, issues, FoldExplanation(e)
+
+	if graphFormat != "" {
+		var buf bytes.Buffer
+		if err := ExportGraph(&buf, ag, graphFormat); err != nil {
+			return nil, err
+		}
+		result.Graph = buf.String()
+	}
+	return result, nil
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,48 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestComplianceCheckerPlugin_Playground(t *testing.T) {
+	c, dir := testPlugin(t)
+	defer os.RemoveAll(dir)
+
+	// the candidate does not require consent for marketing
+	got, err := c.Playground(testRuleBaseWithID("test", false), actionDoc("market"), DOT)
+	if err != nil {
+		t.Fatalf("ComplianceCheckerPlugin.Playground() error = %v", err)
+	}
+	if !got.Lint.Valid || got.RuleBaseID != "test" || got.Compliant != Compliant {
+		t.Errorf("ComplianceCheckerPlugin.Playground() = %+v, want a compliant result", got)
+	}
+	if s := got.Statistics; s == nil || s.Statements == 0 || s.Arguments == 0 || s.Assumptions != 1 {
+		t.Errorf("ComplianceCheckerPlugin.Playground() statistics = %+v", s)
+	}
+	if _, ok := got.Explanation["s0"]; !ok {
+		t.Errorf("ComplianceCheckerPlugin.Playground() explanation = %v, want an explanation of s0", got.Explanation)
+	}
+	if !strings.HasPrefix(got.Graph, "digraph") {
+		t.Errorf("ComplianceCheckerPlugin.Playground() graph = %q, want a DOT graph", got.Graph)
+	}
+
+	// the loaded version of the rulebase is still used
+	if ok, _, err := c.IsCompliant("test", actionDoc("market")); ok || err != nil {
+		t.Errorf("IsCompliant() after Playground() = %v, %v, want false", ok, err)
+	}
+
+	// errors are reported with their lines
+	invalid := strings.Replace(string(testRuleBaseWithID("test", true)), "consentRequired(S)", "consentNeeded(S)", 1)
+	got, err = c.Playground([]byte(invalid), actionDoc("market"), "")
+	if err != nil {
+		t.Fatalf("ComplianceCheckerPlugin.Playground() error = %v", err)
+	}
+	if got.Lint.Valid || got.Statistics != nil || len(got.Lint.Issues) != 1 || got.Lint.Issues[0].Line != 43 {
+		t.Errorf("ComplianceCheckerPlugin.Playground() = %+v, want an error in line 43", got)
+	}
+}
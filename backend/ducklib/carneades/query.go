@@ -0,0 +1,105 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+	"github.com/carneades/carneades-4/src/engine/caes"
+	"github.com/carneades/carneades-4/src/engine/terms"
+)
+
+// QueryResult is a statement of an argument graph matching the pattern of a query,
+// with the bindings of the variables of the pattern, its label and the arguments
+// pro and con the statement, i.e. the arguments of the other positions of its issue.
+type QueryResult struct {
+	Statement string                `json:"statement"`
+	Bindings  map[string]string     `json:"bindings"`
+	Label     string                `json:"label"` // in, out or undecided
+	Pro       []ArgumentExplanation `json:"pro"`
+	Con       []ArgumentExplanation `json:"con"`
+}
+
+// ParseQuery parses the pattern of a query, an atomic formula which may contain
+// variables, e.g. compatiblePurpose(S1,S2). An HTTPError with status 400 is returned
+// if the pattern is not an atomic formula.
+func ParseQuery(pattern string) (terms.Term, error) {
+	t, ok := readAtomicFormula(pattern)
+	if !ok {
+		return nil, structs.NewHTTPError(fmt.Sprintf("The query %s is not an atomic formula", pattern), http.StatusBadRequest)
+	}
+	n := 0
+	return renameAnonymousVariables(t, &n), nil
+}
+
+// renameAnonymousVariables replaces each occurrence of the anonymous variable _ in the
+// term with a distinct variable, since the parser reads it as an atom
+func renameAnonymousVariables(t terms.Term, n *int) terms.Term {
+	switch t := t.(type) {
+	case terms.Atom:
+		if t != "_" {
+			return t
+		}
+		*n++
+		return terms.NewVariable(fmt.Sprintf("_%d", *n))
+	case terms.Compound:
+		args := make([]terms.Term, len(t.Args))
+		for i, arg := range t.Args {
+			args[i] = renameAnonymousVariables(arg, n)
+		}
+		return terms.Compound{Functor: t.Functor, Args: args}
+	case terms.List:
+		l := make(terms.List, len(t))
+		for i, e := range t {
+			l[i] = renameAnonymousVariables(e, n)
+		}
+		return l
+	default:
+		return t
+	}
+}
+
+// Query returns the statements of the argument graph matching the pattern,
+// ordered by statement. Variables are bound to the terms they match, anonymous
+// variables are not included in the bindings. The values of the document in the
+// statements and bindings are decoded.
+func Query(ag *caes.ArgGraph, pattern terms.Term) []QueryResult {
+	vars := pattern.OccurVars()
+	results := []QueryResult{}
+	for id, stmt := range ag.Statements {
+		t, ok := terms.ReadString(id)
+		if !ok {
+			continue
+		}
+		env, ok := terms.Match(pattern, t, nil)
+		if !ok {
+			continue
+		}
+		r := QueryResult{Statement: decodeStatement(id), Bindings: map[string]string{}, Label: stmt.Label.String(),
+			Pro: []ArgumentExplanation{}, Con: []ArgumentExplanation{}}
+		for _, v := range vars {
+			if strings.HasPrefix(v.Name, "_") {
+				continue
+			}
+			if b, ok := terms.GetBinding(v, env); ok {
+				r.Bindings[v.Name] = decodeTerm(b)
+			}
+		}
+		for _, arg := range explainStatement(stmt) {
+			if arg.Pro {
+				r.Pro = append(r.Pro, arg)
+			} else {
+				r.Con = append(r.Con, arg)
+			}
+		}
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Statement < results[j].Statement })
+	return results
+}
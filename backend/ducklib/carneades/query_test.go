@@ -0,0 +1,87 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComplianceCheckerPlugin_Query(t *testing.T) {
+	c, dir := testPlugin(t)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name         string
+		query        string
+		wantBindings []map[string]string
+		wantLabels   []string
+		wantStatus   int
+	}{
+		{"ground", "docConsentRequired", []map[string]string{{}}, []string{"in"}, 0},
+		{"position of issue", "notDocConsentRequired.", []map[string]string{{}}, []string{"out"}, 0},
+		{"variable", "consentRequired(S)", []map[string]string{{"S": "dus(capability,null,identified_data,customer_content,capability,null,market,capability,null,s1,0,false)"}}, []string{"in"}, 0},
+		{"nested variables", "dataUseStatement(dus(_,_,_,_,_,_,A,_,_,ID,_,_))", []map[string]string{{"A": "market", "ID": "s1"}, {"A": "provide", "ID": "s0"}}, []string{"in", "in"}, 0},
+		{"no match", "consentRequired(dus(_,_,_,_,_,_,provide,_,_,_,_,_))", []map[string]string{}, []string{}, 0},
+		{"not atomic", "X", nil, nil, http.StatusBadRequest},
+		{"invalid", "consentRequired(", nil, nil, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		got, err := c.Query("test", actionDoc("provide", "market"), tt.query)
+		if httpStatus(err) != tt.wantStatus {
+			t.Errorf("%q. ComplianceCheckerPlugin.Query() error = %v, want status %v", tt.name, err, tt.wantStatus)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		bindings, labels := []map[string]string{}, []string{}
+		for _, r := range got {
+			bindings = append(bindings, r.Bindings)
+			labels = append(labels, r.Label)
+		}
+		if !reflect.DeepEqual(bindings, tt.wantBindings) {
+			t.Errorf("%q. ComplianceCheckerPlugin.Query() bindings = %v, want %v", tt.name, bindings, tt.wantBindings)
+		}
+		if !reflect.DeepEqual(labels, tt.wantLabels) {
+			t.Errorf("%q. ComplianceCheckerPlugin.Query() labels = %v, want %v", tt.name, labels, tt.wantLabels)
+		}
+	}
+
+	got, err := c.Query("test", actionDoc("provide", "market"), "notDocConsentRequired")
+	if err != nil || len(got) != 1 {
+		t.Fatalf("ComplianceCheckerPlugin.Query() = %v, %v, want 1 result", got, err)
+	}
+	if r := got[0]; len(r.Pro) != 1 || r.Pro[0].Scheme != "docConsent1" || len(r.Con) != 1 || r.Con[0].Scheme != "docConsent2" {
+		t.Errorf("ComplianceCheckerPlugin.Query() arguments = %+v, %+v, want docConsent1 pro and docConsent2 con", r.Pro, r.Con)
+	}
+
+	if _, err := c.Query("other", actionDoc("provide"), "docConsentRequired"); httpStatus(err) != http.StatusNotFound {
+		t.Errorf("ComplianceCheckerPlugin.Query() error = %v for an unknown rulebase, want status %v", err, http.StatusNotFound)
+	}
+}
+
+func TestComplianceCheckerPlugin_Query_decoded(t *testing.T) {
+	c, dir := testPlugin(t)
+	defer os.RemoveAll(dir)
+
+	doc := actionDoc("market")
+	doc.Statements[0].TrackingID = "S-1"
+	got, err := c.Query("test", doc, "consentRequired(dus(_,_,_,_,_,_,_,_,_,ID,_,_))")
+	if err != nil || len(got) != 1 {
+		t.Fatalf("ComplianceCheckerPlugin.Query() = %v, %v, want 1 result", got, err)
+	}
+	if want := map[string]string{"ID": "S-1"}; !reflect.DeepEqual(got[0].Bindings, want) {
+		t.Errorf("ComplianceCheckerPlugin.Query() bindings = %v, want %v", got[0].Bindings, want)
+	}
+	if want := "consentRequired(dus(capability,null,identified_data,customer_content,capability,null,market,capability,null,S-1,0,false))"; got[0].Statement != want {
+		t.Errorf("ComplianceCheckerPlugin.Query() statement = %v, want %v", got[0].Statement, want)
+	}
+	if premises := got[0].Pro[0].Premises; len(premises) != 1 || !strings.Contains(premises[0], ",S-1,") {
+		t.Errorf("ComplianceCheckerPlugin.Query() premises = %v, want the decoded tracking id", premises)
+	}
+}
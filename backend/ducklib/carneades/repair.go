@@ -0,0 +1,350 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"container/heap"
+	"sort"
+	"strings"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+	"github.com/carneades/carneades-4/src/engine/terms"
+)
+
+// Fields of a data use statement which can be changed by an Edit.
+// RemoveStatement denotes the removal of the whole statement.
+const (
+	RemoveStatement  = "statement"
+	QualifierField   = "qualifierCode"
+	UseScopeField    = "useScopeCode"
+	SourceScopeField = "sourceScopeCode"
+	ResultScopeField = "resultScopeCode"
+	ActionField      = "actionCode"
+)
+
+// MaxRepairEvaluations is the default bound on the number of compliance
+// checks performed when searching for a repair
+const MaxRepairEvaluations = 500
+
+// Edit is a change of one field of a data use statement, or its removal
+type Edit struct {
+	TrackingID string  `json:"trackingId"`
+	Field      string  `json:"field"`
+	From       string  `json:"from"`
+	To         string  `json:"to,omitempty"`
+	Cost       float64 `json:"cost"`
+}
+
+// Repair is a set of edits which makes a document compliant
+type Repair struct {
+	Edits          []Edit              `json:"edits"`
+	Cost           float64             `json:"cost"`
+	Document       *NormalizedDocument `json:"-"`                        // the repaired document
+	Denormalized   *structs.Document   `json:"document,omitempty"`       // the repaired document as the author would write it, see Normalizer.Denormalize
+	DroppedClauses []DroppedClause     `json:"droppedClauses,omitempty"` // the clauses of the author removed by the repair
+}
+
+// CostModel assigns a cost to each kind of Edit. Qualifiers are ordered by their
+// position in the taxonomy and scopes by the includes relation of the taxonomy. The
+// cost of narrowing a qualifier or shrinking a scope is proportional to the number of steps.
+type CostModel struct {
+	Remove    float64 // removing a statement
+	Qualifier float64 // per step a qualifier is narrowed, e.g. identified to pseudonymized
+	Scope     float64 // per includes step a scope is shrunk, e.g. service to capability
+	Action    float64 // replacing the action of a statement
+}
+
+// DefaultCostModel prefers narrowing qualifiers to shrinking scopes,
+// shrinking scopes to changing actions and any edit to removing a statement.
+var DefaultCostModel = CostModel{Remove: 10, Qualifier: 1, Scope: 2, Action: 4}
+
+// repairTaxonomy holds the orderings of the taxonomy used to generate edits
+type repairTaxonomy struct {
+	qualifiers []string
+	scopes     map[string][]narrowerScope // the scopes each scope includes, nearest first
+	actions    []string
+}
+
+// narrowerScope is a scope included by another one in the given number of includes steps
+type narrowerScope struct {
+	code  string
+	steps int
+}
+
+// codes returns the codes of the given type of the taxonomy, in taxonomy order
+func codes(tax structs.Taxonomy, typ string) []string {
+	l := []string{}
+	for _, e := range tax[typ] {
+		l = append(l, e.Code)
+	}
+	return l
+}
+
+// narrowerScopes maps each scope of the taxonomy to the scopes it includes, directly or
+// transitively, nearest first. Equivalent scopes do not include each other, so shrinking
+// a scope never moves it to an equivalent or unrelated scope.
+func narrowerScopes(tax structs.Taxonomy) map[string][]narrowerScope {
+	includes := make(map[string][]string)
+	for _, e := range tax["scope"] {
+		includes[e.Code] = e.Includes
+	}
+	m := make(map[string][]narrowerScope)
+	for _, e := range tax["scope"] {
+		seen := map[string]bool{e.Code: true}
+		level := []string{e.Code}
+		for steps := 1; len(level) > 0; steps++ {
+			next := []string{}
+			for _, code := range level {
+				for _, narrower := range includes[code] {
+					if !seen[narrower] {
+						seen[narrower] = true
+						m[e.Code] = append(m[e.Code], narrowerScope{narrower, steps})
+						next = append(next, narrower)
+					}
+				}
+			}
+			level = next
+		}
+	}
+	return m
+}
+
+func indexOf(l []string, code string) int {
+	for i, c := range l {
+		if c == code {
+			return i
+		}
+	}
+	return -1
+}
+
+// consentPredicates returns the explanation predicates of single statements from which the
+// theory concludes that the document requires consent, i.e. those in the premises of the
+// schemes concluding docConsentRequired, e.g. consentRequired for consentRequired(S)
+func consentPredicates(theory *Theory) []string {
+	explained := make(map[string]bool)
+	for _, p := range theory.Schema {
+		if !p.Relation {
+			explained[p.Predicate] = true
+		}
+	}
+	predicates := []string{}
+	found := make(map[string]bool)
+	for _, scheme := range theory.ArgSchemes {
+		if indexOf(scheme.Conclusions, "docConsentRequired") < 0 {
+			continue
+		}
+		for _, premise := range scheme.Premises {
+			t, ok := terms.ReadString(premise)
+			if !ok {
+				continue
+			}
+			c, ok := t.(terms.Compound)
+			if ok && len(c.Args) == 1 && c.Args[0].Type() == terms.VariableType && explained[c.Functor] && !found[c.Functor] {
+				found[c.Functor] = true
+				predicates = append(predicates, c.Functor)
+			}
+		}
+	}
+	return predicates
+}
+
+type repairNode struct {
+	repair Repair
+	edited map[string]bool // keys tid|field of the fields already edited
+	seq    int
+}
+
+// repairQueue is a priority queue of repairNodes ordered by cost and,
+// for equal costs, by the order in which the nodes were pushed
+type repairQueue []*repairNode
+
+func (q repairQueue) Len() int { return len(q) }
+func (q repairQueue) Less(i, j int) bool {
+	if q[i].repair.Cost != q[j].repair.Cost {
+		return q[i].repair.Cost < q[j].repair.Cost
+	}
+	return q[i].seq < q[j].seq
+}
+func (q repairQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *repairQueue) Push(x interface{}) { *q = append(*q, x.(*repairNode)) }
+func (q *repairQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+// editKey identifies a set of edits, independent of the order of the edits
+func editKey(edits []Edit) string {
+	keys := make([]string, len(edits))
+	for i, e := range edits {
+		keys[i] = e.TrackingID + "|" + e.Field + "|" + e.To
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ";")
+}
+
+// applyEdit returns a copy of the document with the edit applied to
+// the statement at index i
+func applyEdit(d *NormalizedDocument, i int, e Edit) *NormalizedDocument {
+	if e.Field == RemoveStatement {
+		d2, _ := removeStatement(d, i)
+		return d2
+	}
+	d2 := *d
+	d2.Statements = make([]NormalizedStatement, len(d.Statements))
+	copy(d2.Statements, d.Statements)
+	s := &d2.Statements[i]
+	switch e.Field {
+	case QualifierField:
+		s.QualifierCode = e.To
+	case UseScopeField:
+		s.UseScopeCode = e.To
+	case SourceScopeField:
+		s.SourceScopeCode = e.To
+	case ResultScopeField:
+		s.ResultScopeCode = e.To
+	case ActionField:
+		s.ActionCode = e.To
+	}
+	return &d2
+}
+
+// edits returns the candidate edits of the statement s of document d
+func (t repairTaxonomy) edits(d *NormalizedDocument, s NormalizedStatement, costs CostModel) []Edit {
+	result := []Edit{{TrackingID: s.TrackingID, Field: RemoveStatement, From: s.TrackingID, Cost: costs.Remove}}
+
+	// narrow the qualifier; unqualified data is treated like identified data
+	q := s.QualifierCode
+	if q == "unqualified" {
+		q = "identified_data"
+	}
+	if i := indexOf(t.qualifiers, q); i >= 0 {
+		for j := i + 1; j < len(t.qualifiers); j++ {
+			result = append(result, Edit{s.TrackingID, QualifierField, s.QualifierCode, t.qualifiers[j], costs.Qualifier * float64(j-i)})
+		}
+	}
+
+	// shrink the scopes, resolving custom scope codes with the is a relationships
+	scopes := []struct {
+		field string
+		code  string
+	}{
+		{UseScopeField, s.UseScopeCode},
+		{SourceScopeField, s.SourceScopeCode},
+		{ResultScopeField, s.ResultScopeCode},
+	}
+	for _, sc := range scopes {
+		code := sc.code
+		if std, ok := d.IsA[code]; ok {
+			code = std
+		}
+		for _, narrower := range t.scopes[code] {
+			result = append(result, Edit{s.TrackingID, sc.field, sc.code, narrower.code, costs.Scope * float64(narrower.steps)})
+		}
+	}
+
+	// replace the action
+	for _, a := range t.actions {
+		if a != s.ActionCode {
+			result = append(result, Edit{s.TrackingID, ActionField, s.ActionCode, a, costs.Action})
+		}
+	}
+	return result
+}
+
+/*
+Repair searches for the cheapest set of edits of the data use statements of a
+non-compliant document which makes the document compliant, i.e. makes the
+notDocConsentRequired statement in. Besides removing statements, the edits
+narrow qualifiers (e.g. identified to pseudonymized or anonymized data), shrink
+scopes to the scopes they include in the taxonomy, or replace actions. The
+costs of the edits are defined by the cost model.
+The search is a uniform-cost search, so the first compliant document found
+is a cheapest repair. To keep the search space small, only the statements which
+require consent in the document being expanded are edited and each field of a
+statement is edited at most once.
+If the document is already compliant, true is returned. If no repair is found
+within maxEvaluations compliance checks, the returned repair is nil.
+*/
+func (c ComplianceChecker) Repair(theory *Theory, doc *NormalizedDocument, tax structs.Taxonomy, costs CostModel, maxEvaluations int) (bool, *Repair, error) {
+	compliant, exp, err := c.IsCompliant(theory, doc)
+	if err != nil {
+		return false, nil, err
+	}
+	if compliant {
+		return true, nil, nil
+	}
+
+	t := repairTaxonomy{
+		qualifiers: codes(tax, "qualifier"),
+		scopes:     narrowerScopes(tax),
+		actions:    codes(tax, "dataUseCategory"),
+	}
+
+	consent := consentPredicates(theory)
+	queue := &repairQueue{}
+	visited := map[string]bool{"": true}
+	seq := 0
+
+	expand := func(n *repairNode, exp Explanation) {
+		// edit the statements requiring consent, or all statements
+		// if the explanation does not single out any statement
+		candidates := []int{}
+		for i, s := range n.repair.Document.Statements {
+			for _, p := range consent {
+				if exp[s.TrackingID][p].Value {
+					candidates = append(candidates, i)
+					break
+				}
+			}
+		}
+		if len(candidates) == 0 {
+			for i := range n.repair.Document.Statements {
+				candidates = append(candidates, i)
+			}
+		}
+		for _, i := range candidates {
+			s := n.repair.Document.Statements[i]
+			for _, e := range t.edits(n.repair.Document, s, costs) {
+				if n.edited[s.TrackingID+"|"+e.Field] {
+					continue
+				}
+				edits := append(append([]Edit{}, n.repair.Edits...), e)
+				key := editKey(edits)
+				if visited[key] {
+					continue
+				}
+				visited[key] = true
+				edited := map[string]bool{s.TrackingID + "|" + e.Field: true}
+				for k := range n.edited {
+					edited[k] = true
+				}
+				seq++
+				heap.Push(queue, &repairNode{
+					repair: Repair{Edits: edits, Cost: n.repair.Cost + e.Cost, Document: applyEdit(n.repair.Document, i, e)},
+					edited: edited,
+					seq:    seq,
+				})
+			}
+		}
+	}
+
+	expand(&repairNode{repair: Repair{Edits: []Edit{}, Document: doc}, edited: map[string]bool{}}, exp)
+
+	for evaluations := 0; queue.Len() > 0 && evaluations < maxEvaluations; evaluations++ {
+		n := heap.Pop(queue).(*repairNode)
+		compliant, exp, err := c.IsCompliant(theory, n.repair.Document)
+		if err != nil {
+			return false, nil, err
+		}
+		if compliant {
+			return false, &n.repair, nil
+		}
+		expand(n, exp)
+	}
+	return false, nil, nil
+}
@@ -0,0 +1,145 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+)
+
+func testRepairTaxonomy() repairTaxonomy {
+	return repairTaxonomy{
+		qualifiers: []string{"identified_data", "pseudonymized_data", "anonymized_data"},
+		scopes: narrowerScopes(structs.Taxonomy{"scope": {
+			{Code: "capability", Category: "1"},
+			{Code: "service", Category: "2", Includes: []string{"capability"}},
+			{Code: "services_agreement", Category: "3", Includes: []string{"service"}},
+			{Code: "third_party_services", Category: "6", Equivalent: []string{"third_party_partners"}},
+			{Code: "third_party_partners", Category: "7", Equivalent: []string{"third_party_services"}},
+		}}),
+		actions: []string{"provide", "market"},
+	}
+}
+
+func repairStatement(qualifier string, scope string, action string) NormalizedStatement {
+	return NormalizedStatement{Statement: structs.Statement{
+		TrackingID:      "s1",
+		QualifierCode:   qualifier,
+		UseScopeCode:    "capability",
+		SourceScopeCode: "capability",
+		ResultScopeCode: scope,
+		ActionCode:      action,
+	}}
+}
+
+func Test_repairTaxonomy_edits(t *testing.T) {
+	costs := CostModel{Remove: 10, Qualifier: 1, Scope: 2, Action: 4}
+	tests := []struct {
+		name string
+		doc  *NormalizedDocument
+		stmt NormalizedStatement
+		want []Edit
+	}{
+		{
+			"narrowest statement",
+			&NormalizedDocument{},
+			repairStatement("anonymized_data", "capability", "provide"),
+			[]Edit{
+				{"s1", RemoveStatement, "s1", "", 10},
+				{"s1", ActionField, "provide", "market", 4},
+			},
+		},
+		{
+			"qualifier and scope",
+			&NormalizedDocument{},
+			repairStatement("identified_data", "service", "provide"),
+			[]Edit{
+				{"s1", RemoveStatement, "s1", "", 10},
+				{"s1", QualifierField, "identified_data", "pseudonymized_data", 1},
+				{"s1", QualifierField, "identified_data", "anonymized_data", 2},
+				{"s1", ResultScopeField, "service", "capability", 2},
+				{"s1", ActionField, "provide", "market", 4},
+			},
+		},
+		{
+			"unqualified and custom scope",
+			&NormalizedDocument{IsA: map[string]string{"partner_x": "third_party_partners"}},
+			repairStatement("unqualified", "partner_x", "market"),
+			[]Edit{
+				{"s1", RemoveStatement, "s1", "", 10},
+				{"s1", QualifierField, "unqualified", "pseudonymized_data", 1},
+				{"s1", QualifierField, "unqualified", "anonymized_data", 2},
+				{"s1", ActionField, "market", "provide", 4},
+			},
+		},
+		{
+			"transitively included scopes",
+			&NormalizedDocument{},
+			repairStatement("anonymized_data", "services_agreement", "provide"),
+			[]Edit{
+				{"s1", RemoveStatement, "s1", "", 10},
+				{"s1", ResultScopeField, "services_agreement", "service", 2},
+				{"s1", ResultScopeField, "services_agreement", "capability", 4},
+				{"s1", ActionField, "provide", "market", 4},
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := testRepairTaxonomy().edits(tt.doc, tt.stmt, costs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q. repairTaxonomy.edits() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func Test_consentPredicates(t *testing.T) {
+	if got, want := consentPredicates(testTheory(t)), []string{"consentRequired"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("consentPredicates() = %v, want %v", got, want)
+	}
+	renamed := strings.Replace(testRuleBase, "consentRequired", "needsConsent", -1)
+	theory, err := MakeComplianceChecker().GetTheory("renamed", "1", strings.NewReader(renamed))
+	if err != nil {
+		t.Fatalf("GetTheory() error = %v", err)
+	}
+	if got, want := consentPredicates(theory), []string{"needsConsent"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("consentPredicates() of a rulebase naming the predicate needsConsent = %v, want %v", got, want)
+	}
+}
+
+func Test_applyEdit(t *testing.T) {
+	doc := &NormalizedDocument{Statements: []NormalizedStatement{
+		repairStatement("identified_data", "service", "market"),
+	}}
+	tests := []struct {
+		name string
+		edit Edit
+		want []NormalizedStatement
+	}{
+		{"qualifier", Edit{"s1", QualifierField, "identified_data", "anonymized_data", 2}, []NormalizedStatement{repairStatement("anonymized_data", "service", "market")}},
+		{"scope", Edit{"s1", ResultScopeField, "service", "capability", 2}, []NormalizedStatement{repairStatement("identified_data", "capability", "market")}},
+		{"action", Edit{"s1", ActionField, "market", "provide", 4}, []NormalizedStatement{repairStatement("identified_data", "service", "provide")}},
+		{"remove", Edit{"s1", RemoveStatement, "s1", "", 10}, []NormalizedStatement{}},
+	}
+	for _, tt := range tests {
+		if got := applyEdit(doc, 0, tt.edit); !reflect.DeepEqual(got.Statements, tt.want) {
+			t.Errorf("%q. applyEdit() = %v, want %v", tt.name, got.Statements, tt.want)
+		}
+	}
+	if doc.Statements[0].QualifierCode != "identified_data" || doc.Statements[0].ActionCode != "market" {
+		t.Errorf("applyEdit() modified the original document: %v", doc.Statements)
+	}
+}
+
+func Test_editKey(t *testing.T) {
+	a := Edit{"s1", QualifierField, "identified_data", "anonymized_data", 2}
+	b := Edit{"s2", RemoveStatement, "s2", "", 10}
+	if editKey([]Edit{a, b}) != editKey([]Edit{b, a}) {
+		t.Errorf("editKey() depends on the order of the edits")
+	}
+	if editKey([]Edit{a}) == editKey([]Edit{b}) {
+		t.Errorf("editKey() is the same for different edits")
+	}
+}
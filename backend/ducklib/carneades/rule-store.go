@@ -0,0 +1,128 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/carneades/carneades-4/src/engine/caes"
+	"github.com/carneades/carneades-4/src/engine/terms"
+	chr "github.com/hfried/GoCHR/src/engine/CHR"
+)
+
+// ruleStores caches the CHR rule stores compiled from the theories of a ComplianceChecker,
+// so that a theory is not compiled into CHR rules again for each argument graph. A rule
+// store keeps the state of the inference using it, so the stores of a theory are pooled
+// and each store is used by one inference at a time.
+type ruleStores struct {
+	lock  sync.Mutex
+	pools map[*caes.Theory]*sync.Pool
+}
+
+func newRuleStores() *ruleStores {
+	return &ruleStores{pools: make(map[*caes.Theory]*sync.Pool)}
+}
+
+// get returns a rule store of the theory, compiling it if no store is free
+func (r *ruleStores) get(theory *caes.Theory) *chr.RuleStore {
+	r.lock.Lock()
+	pool, ok := r.pools[theory]
+	if !ok {
+		pool = &sync.Pool{New: func() interface{} { return caes.TheoryToRuleStore(theory) }}
+		r.pools[theory] = pool
+	}
+	r.lock.Unlock()
+	return pool.Get().(*chr.RuleStore)
+}
+
+// put returns a rule store got for the theory, unless the theory was removed meanwhile
+func (r *ruleStores) put(theory *caes.Theory, rs *chr.RuleStore) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if pool, ok := r.pools[theory]; ok {
+		pool.Put(rs)
+	}
+}
+
+func (r *ruleStores) remove(theory *caes.Theory) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.pools, theory)
+}
+
+func (r *ruleStores) len() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.pools)
+}
+
+// infer derives arguments by applying the theory of the argument graph to its assumptions
+// and the arguments already in the graph, like ag.Infer, but with a cached rule store of
+// the theory. Arguments without a scheme, i.e. those of exceptions, are not used as goals.
+func (c ComplianceChecker) infer(ag *caes.ArgGraph) error {
+	theory := ag.Theory
+	if len(theory.ArgSchemes) != 0 {
+		// the arguments in the graph are goals, so that they are not constructed again
+		// and the inference engine can construct undercutters
+		prevArgs := map[string]bool{}
+		for _, a := range ag.Arguments {
+			if a != nil && a.Scheme != nil {
+				prevArgs["argument("+a.Scheme.Id+",["+strings.Join(a.Parameters, ",")+"])"] = true
+			}
+		}
+		goals := []string{"go"}
+		goals = append(goals, ag.Assumptions...)
+		for k := range prevArgs {
+			goals = append(goals, k)
+		}
+
+		rs := c.ruleStores.get(theory)
+		success, store, err := rs.Infer(goals, caes.MAXRULEAPPS)
+		c.ruleStores.put(theory, rs)
+		if err != nil {
+			return err
+		}
+		if !success {
+			return nil
+		}
+		for _, s := range store {
+			if prevArgs[s] {
+				continue
+			}
+			if scheme, values, ok := argumentTerm(s); ok {
+				ag.InstantiateScheme(scheme, values)
+				prevArgs[s] = true
+			}
+		}
+	}
+
+	// without argument schemes, Infer only applies the issue schemes of the theory
+	issues := *theory
+	issues.ArgSchemes = nil
+	ag.Theory = &issues
+	defer func() { ag.Theory = theory }()
+	return ag.Infer()
+}
+
+// argumentTerm returns the scheme id and the values of the variables of the scheme
+// if s is an argument term of the store of the inference engine, argument(S,[V1,...])
+func argumentTerm(s string) (string, []string, bool) {
+	t, ok := terms.ReadString(s)
+	if !ok {
+		return "", nil, false
+	}
+	c, ok := t.(terms.Compound)
+	if !ok || c.Functor != "argument" || len(c.Args) != 2 {
+		return "", nil, false
+	}
+	values := []string{}
+	if l, ok := c.Args[1].(terms.List); ok {
+		for _, v := range l {
+			values = append(values, v.String())
+		}
+	}
+	return c.Args[0].String(), values, true
+}
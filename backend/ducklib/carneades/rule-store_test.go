@@ -0,0 +1,161 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/carneades/carneades-4/src/engine/caes"
+)
+
+// rb2 is the rulebase of the repository used by the benchmarks
+var rb2 = filepath.Join("..", "..", "..", "RuleBases", "rb2.yml")
+
+func testRb2Theory(tb testing.TB, c *ComplianceChecker) *Theory {
+	f, err := os.Open(rb2)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	theory, err := c.GetTheory("123", "1", f)
+	if err != nil {
+		tb.Fatalf("GetTheory() error = %v", err)
+	}
+	return theory
+}
+
+// uncachedArgumentGraph constructs the argument graph with ag.Infer,
+// which compiles the theory into a rule store for each graph
+func uncachedArgumentGraph(theory *Theory, doc *NormalizedDocument) (*caes.ArgGraph, error) {
+	ag := assumeDocument(theory, doc)
+	if err := ag.Infer(); err != nil {
+		return nil, err
+	}
+	ag.ApplyLabelling(ag.GroundedLabelling())
+	return ag, nil
+}
+
+var ruleStoreDocs = []*NormalizedDocument{
+	actionDoc("provide"),
+	actionDoc("provide", "market"),
+	actionDoc("collect", "market", "share"),
+	actionDoc(),
+}
+
+func labels(ag *caes.ArgGraph) map[string]string {
+	m := make(map[string]string, len(ag.Statements))
+	for id, stmt := range ag.Statements {
+		// the ids of the undercutters depend on the order in which arguments were constructed
+		if !strings.HasPrefix(id, "undercut(") {
+			m[id] = stmt.Label.String()
+		}
+	}
+	return m
+}
+
+func TestComplianceChecker_infer(t *testing.T) {
+	c := MakeComplianceChecker()
+	theory := testRb2Theory(t, c)
+	for i, doc := range ruleStoreDocs {
+		want, err := uncachedArgumentGraph(theory, doc)
+		if err != nil {
+			t.Fatalf("%d. Infer() error = %v", i, err)
+		}
+		// the second graph reuses the rule store of the first
+		for j := 0; j < 2; j++ {
+			got, err := c.ArgumentGraph(theory, doc)
+			if err != nil {
+				t.Fatalf("%d. ArgumentGraph() error = %v", i, err)
+			}
+			if len(got.Arguments) != len(want.Arguments) || len(got.Issues) != len(want.Issues) {
+				t.Errorf("%d. ArgumentGraph() arguments, issues = %v, %v, want %v, %v", i,
+					len(got.Arguments), len(got.Issues), len(want.Arguments), len(want.Issues))
+			}
+			if !reflect.DeepEqual(labels(got), labels(want)) {
+				t.Errorf("%d. ArgumentGraph() labels differ from those of ag.Infer", i)
+			}
+		}
+	}
+}
+
+func TestComplianceChecker_infer_concurrent(t *testing.T) {
+	c := MakeComplianceChecker()
+	theory := testRb2Theory(t, c)
+	want := make([]Verdict, len(ruleStoreDocs))
+	for i, doc := range ruleStoreDocs {
+		r, err := c.Check(theory, doc)
+		if err != nil {
+			t.Fatalf("%d. Check() error = %v", i, err)
+		}
+		want[i] = r.Verdict
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, doc := range ruleStoreDocs {
+				r, err := c.Check(theory, doc)
+				if err != nil || r.Verdict != want[i] {
+					t.Errorf("%d. Check() = %v, %v, want %v", i, r.Verdict, err, want[i])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestComplianceChecker_ruleStoresEvicted(t *testing.T) {
+	c := MakeComplianceChecker()
+	theory := testRb2Theory(t, c)
+	if _, err := c.Check(theory, actionDoc("provide")); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if got := c.ruleStores.len(); got != 1 {
+		t.Errorf("ruleStores = %v, want %v", got, 1)
+	}
+	c.Theories.Remove("123")
+	if got := c.ruleStores.len(); got != 0 {
+		t.Errorf("ruleStores after Remove() = %v, want %v", got, 0)
+	}
+}
+
+func BenchmarkCheck_rb2(b *testing.B) {
+	c := MakeComplianceChecker()
+	theory := testRb2Theory(b, c)
+	doc := actionDoc("collect", "market", "share")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.ArgumentGraph(theory, doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCheck_rb2Uncached(b *testing.B) {
+	c := MakeComplianceChecker()
+	theory := testRb2Theory(b, c)
+	doc := actionDoc("collect", "market", "share")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := uncachedArgumentGraph(theory, doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkTheoryToRuleStore_rb2 measures the compilation saved for each check by the cached rule stores
+func BenchmarkTheoryToRuleStore_rb2(b *testing.B) {
+	theory := testRb2Theory(b, MakeComplianceChecker())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		caes.TheoryToRuleStore(theory.Theory)
+	}
+}
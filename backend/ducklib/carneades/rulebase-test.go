@@ -0,0 +1,286 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+	"gopkg.in/yaml.v2"
+)
+
+// RuleBaseTestSuffix is the suffix of the names of rulebase test files. The test cases
+// of the rulebase rb.yml are in the file rb_test.yml in the same directory. Test files
+// are not loaded as rulebases.
+const RuleBaseTestSuffix = "_test.yml"
+
+// IsRuleBaseTestFile reports whether the file name is the name of a rulebase test file
+func IsRuleBaseTestFile(name string) bool {
+	return strings.HasSuffix(name, RuleBaseTestSuffix)
+}
+
+// RuleBaseTestSuite is a list of test cases of a rulebase, e.g.
+//
+//	rulebase: "123"   # optional, the meta id of the rulebase next to the test file by default
+//	cases:
+//	  - name: marketing requires consent
+//	    expected: NON_COMPLIANT
+//	    document:
+//	      locale: en
+//	      statements:
+//	        - trackingId: s1
+//	          actionCode: market
+//	          ...
+//	    explanation:
+//	      s1:
+//	        consentRequired: true
+//	        pii: {value: true, assumed: false}
+//
+// The document is in the JSON format of structs.Document. Only the fields of the
+// explanations listed in the test case are compared, where a boolean is compared with
+// the value of a BoolValue, a list is compared with the related statements of a
+// BoolValue and lists are compared ignoring their order.
+type RuleBaseTestSuite struct {
+	Filename string
+	RuleBase string
+	Cases    []RuleBaseTestCase
+}
+
+// RuleBaseTestCase is a document with its expected compliance and explanation
+type RuleBaseTestCase struct {
+	Name        string
+	Expected    string // COMPLIANT, NON_COMPLIANT or UNDECIDED
+	Document    structs.Document
+	Explanation map[string]map[string]interface{} // expected explanation fields, by statement tracking id
+}
+
+// RuleBaseTestResult is the result of running a RuleBaseTestCase. Diffs lists the
+// differences between the expected and the actual results. Err is set if the
+// document could not be checked.
+type RuleBaseTestResult struct {
+	Case     string
+	Verdict  string
+	Diffs    []string
+	Err      error
+	Duration time.Duration
+}
+
+// Passed reports whether the test case was checked and met all its expectations
+func (r RuleBaseTestResult) Passed() bool {
+	return r.Err == nil && len(r.Diffs) == 0
+}
+
+// ruleBaseTestFile is a RuleBaseTestSuite as written in YAML
+type ruleBaseTestFile struct {
+	RuleBase string `yaml:"rulebase"`
+	Cases    []struct {
+		Name        string      `yaml:"name"`
+		Expected    string      `yaml:"expected"`
+		Document    interface{} `yaml:"document"`
+		Explanation interface{} `yaml:"explanation"`
+	} `yaml:"cases"`
+}
+
+// LoadRuleBaseTests reads the rulebase test file at path
+func LoadRuleBaseTests(path string) (*RuleBaseTestSuite, error) {
+	dat, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tf := ruleBaseTestFile{}
+	if err := yaml.Unmarshal(dat, &tf); err != nil {
+		return nil, fmt.Errorf("Could not parse the test file %s: %s", path, err)
+	}
+
+	suite := &RuleBaseTestSuite{Filename: filepath.Base(path), RuleBase: tf.RuleBase}
+	if suite.RuleBase == "" {
+		rbPath := strings.TrimSuffix(path, RuleBaseTestSuffix) + ".yml"
+		src, err := ioutil.ReadFile(rbPath)
+		if err != nil {
+			return nil, fmt.Errorf("The test file %s names no rulebase and the rulebase %s could not be read: %s", path, rbPath, err)
+		}
+		desc, err := parseRuleBaseDescription(src)
+		if err != nil {
+			return nil, err
+		}
+		suite.RuleBase = desc.ID
+	}
+
+	for i, c := range tf.Cases {
+		tc := RuleBaseTestCase{Name: c.Name, Expected: c.Expected}
+		if tc.Name == "" {
+			tc.Name = fmt.Sprintf("case %d", i+1)
+		}
+		switch Verdict(tc.Expected) {
+		case Compliant, NonCompliant, Undecided:
+		default:
+			return nil, fmt.Errorf("%s: %s: expected must be COMPLIANT, NON_COMPLIANT or UNDECIDED, not %q", path, tc.Name, tc.Expected)
+		}
+		if err := convertYAML(c.Document, &tc.Document); err != nil {
+			return nil, fmt.Errorf("%s: %s: invalid document: %s", path, tc.Name, err)
+		}
+		if err := convertYAML(c.Explanation, &tc.Explanation); err != nil {
+			return nil, fmt.Errorf("%s: %s: invalid explanation: %s", path, tc.Name, err)
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	return suite, nil
+}
+
+// convertYAML converts a value decoded from YAML into v, by its JSON encoding
+func convertYAML(in interface{}, v interface{}) error {
+	dat, err := json.Marshal(jsonValue(in))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(dat, v)
+}
+
+// jsonValue replaces the maps with interface keys of values decoded
+// from YAML, which cannot be encoded as JSON, with maps with string keys
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = jsonValue(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = jsonValue(e)
+		}
+		return l
+	}
+	return v
+}
+
+// RunRuleBaseTests checks the documents of the test cases of the suite, normalized with
+// normalize, against the rulebase of the suite
+func (c *ComplianceCheckerPlugin) RunRuleBaseTests(suite *RuleBaseTestSuite, normalize func(structs.Document) (*NormalizedDocument, error)) []RuleBaseTestResult {
+	results := make([]RuleBaseTestResult, 0, len(suite.Cases))
+	for _, tc := range suite.Cases {
+		start := time.Now()
+		result := c.runRuleBaseTest(suite.RuleBase, tc, normalize)
+		result.Duration = time.Since(start)
+		results = append(results, result)
+	}
+	return results
+}
+
+func (c *ComplianceCheckerPlugin) runRuleBaseTest(ruleBaseID string, tc RuleBaseTestCase, normalize func(structs.Document) (*NormalizedDocument, error)) RuleBaseTestResult {
+	result := RuleBaseTestResult{Case: tc.Name}
+	doc, err := normalize(tc.Document)
+	if err != nil {
+		result.Err = fmt.Errorf("Could not normalize the document: %s", err)
+		return result
+	}
+	r, err := c.Check(ruleBaseID, doc)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	exp := r.Explanation
+	result.Verdict = string(r.Verdict)
+	if result.Verdict != tc.Expected {
+		result.Diffs = append(result.Diffs, fmt.Sprintf("verdict: expected %s, got %s", tc.Expected, result.Verdict))
+	}
+
+	// compare the explanations as they are returned by the API
+	actual := make(map[string]map[string]interface{})
+	if err := convertYAML(FoldExplanation(exp), &actual); err != nil {
+		result.Err = err
+		return result
+	}
+	ids := make([]string, 0, len(tc.Explanation))
+	for id := range tc.Explanation {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		got, ok := actual[id]
+		if !ok {
+			result.Diffs = append(result.Diffs, fmt.Sprintf("%s: the statement has no explanation", id))
+			continue
+		}
+		fields := make([]string, 0, len(tc.Explanation[id]))
+		for f := range tc.Explanation[id] {
+			fields = append(fields, f)
+		}
+		sort.Strings(fields)
+		for _, f := range fields {
+			want := tc.Explanation[id][f]
+			v, ok := got[f]
+			if !ok {
+				result.Diffs = append(result.Diffs, fmt.Sprintf("%s.%s: unknown explanation field", id, f))
+			} else if !matchExpected(want, v) {
+				result.Diffs = append(result.Diffs, fmt.Sprintf("%s.%s: expected %s, got %s", id, f, jsonString(want), jsonString(v)))
+			}
+		}
+	}
+	return result
+}
+
+// matchExpected reports whether the actual value of an explanation field,
+// decoded from JSON, matches the expected value
+func matchExpected(want interface{}, got interface{}) bool {
+	switch w := want.(type) {
+	case bool:
+		// the value of a BoolValue
+		if g, ok := got.(map[string]interface{}); ok {
+			return g["value"] == w
+		}
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, v := range w {
+			if !matchExpected(v, g[k]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		if g, ok := got.(map[string]interface{}); ok {
+			// the related statements of a BoolValue
+			got = g["statements"]
+		}
+		g, ok := got.([]interface{})
+		if got == nil {
+			g, ok = []interface{}{}, true
+		}
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		return reflect.DeepEqual(sortedStrings(w), sortedStrings(g))
+	}
+	return reflect.DeepEqual(want, got)
+}
+
+func sortedStrings(l []interface{}) []string {
+	s := make([]string, len(l))
+	for i, e := range l {
+		s[i] = fmt.Sprint(e)
+	}
+	sort.Strings(s)
+	return s
+}
+
+func jsonString(v interface{}) string {
+	dat, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(dat)
+}
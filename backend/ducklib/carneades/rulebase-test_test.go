@@ -0,0 +1,122 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+)
+
+const testRuleBaseTests = `
+cases:
+  - name: provide
+    expected: COMPLIANT
+    document:
+      statements:
+        - actionCode: provide
+    explanation:
+      s0:
+        consentRequired: {value: true, assumed: true}
+  - name: market
+    expected: NON_COMPLIANT
+    document:
+      statements:
+        - actionCode: market
+    explanation:
+      s0:
+        consentRequired: {value: true, assumed: false}
+  - name: wrong verdict
+    expected: COMPLIANT
+    document:
+      statements:
+        - actionCode: market
+  - name: wrong explanation
+    expected: NON_COMPLIANT
+    document:
+      statements:
+        - actionCode: market
+    explanation:
+      s0:
+        consentRequired: false
+        unknownField: true
+      s1:
+        consentRequired: false
+`
+
+// testNormalize normalizes a document by the action codes of its statements only
+func testNormalize(doc structs.Document) (*NormalizedDocument, error) {
+	actions := []string{}
+	for _, s := range doc.Statements {
+		actions = append(actions, s.ActionCode)
+	}
+	return actionDoc(actions...), nil
+}
+
+func TestComplianceCheckerPlugin_RunRuleBaseTests(t *testing.T) {
+	c, dir := testPlugin(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test"+RuleBaseTestSuffix)
+	if err := ioutil.WriteFile(path, []byte(testRuleBaseTests), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the test file is not loaded as a rulebase
+	c.Reload()
+	if len(c.RuleBaseDescriptions()) != 1 {
+		t.Errorf("Reload() rulebases = %v, want only test", c.RuleBaseDescriptions())
+	}
+
+	suite, err := LoadRuleBaseTests(path)
+	if err != nil {
+		t.Fatalf("LoadRuleBaseTests() error = %v", err)
+	}
+	if suite.RuleBase != "test" {
+		t.Errorf("LoadRuleBaseTests() rulebase = %v, want test", suite.RuleBase)
+	}
+	results := c.RunRuleBaseTests(suite, testNormalize)
+	wantDiffs := []int{0, 0, 1, 3}
+	if len(results) != len(wantDiffs) {
+		t.Fatalf("RunRuleBaseTests() returned %d results, want %d", len(results), len(wantDiffs))
+	}
+	for i, r := range results {
+		if r.Err != nil || len(r.Diffs) != wantDiffs[i] {
+			t.Errorf("%q. RunRuleBaseTests() = %v, %v, want %d diffs", r.Case, r.Diffs, r.Err, wantDiffs[i])
+		}
+		if r.Passed() != (wantDiffs[i] == 0) {
+			t.Errorf("%q. RuleBaseTestResult.Passed() = %v", r.Case, r.Passed())
+		}
+	}
+}
+
+func TestLoadRuleBaseTests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "duckRulebaseTests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		src     string
+		wantErr bool
+	}{
+		{"valid", "rulebase: test\ncases:\n  - expected: COMPLIANT\n", false},
+		{"no rulebase", "cases:\n  - expected: COMPLIANT\n", true},
+		{"invalid expectation", "rulebase: test\ncases:\n  - expected: MAYBE\n", true},
+		{"invalid document", "rulebase: test\ncases:\n  - expected: COMPLIANT\n    document: [1]\n", true},
+		{"malformed", "cases: [\n", true},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, "x"+RuleBaseTestSuffix)
+		if err := ioutil.WriteFile(path, []byte(tt.src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadRuleBaseTests(path); (err != nil) != tt.wantErr {
+			t.Errorf("%q. LoadRuleBaseTests() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Watch polls the RuleBaseDir every interval, until stop is closed, and reloads
+// the rulebases whose files have changed. See Reload.
+func (c *ComplianceCheckerPlugin) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	rejected := make(map[string]string)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.reload(rejected)
+		}
+	}
+}
+
+// Reload compares the files of the RuleBaseDir with the loaded rulebases. The
+// rulebases of new files, and of files whose content hash is not the revision of
+// the loaded rulebase, are compiled and loaded. The rulebases of deleted files are
+// unloaded. If a file cannot be compiled, the error is logged and the currently
+// loaded version of its rulebase is kept.
+func (c *ComplianceCheckerPlugin) Reload() {
+	c.reload(make(map[string]string))
+}
+
+// reload reloads the changed rulebase files. Files which could not be compiled
+// are recorded in rejected, by name and revision, to log the error only once.
+func (c *ComplianceCheckerPlugin) reload(rejected map[string]string) {
+	files, err := ioutil.ReadDir(c.RuleBaseDir)
+	if err != nil {
+		log.Printf("Could not read the rulebase directory %s: %s", c.RuleBaseDir, err)
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	loaded := make(map[string]RuleBaseDescription)
+	for _, rb := range c.RuleBases {
+		loaded[rb.Filename] = rb
+	}
+	present := make(map[string]bool)
+	for _, file := range files {
+		if file.IsDir() || IsRuleBaseTestFile(file.Name()) {
+			continue
+		}
+		present[file.Name()] = true
+		dat, err := ioutil.ReadFile(filepath.Join(c.RuleBaseDir, file.Name()))
+		if err != nil {
+			log.Printf("Could not read the rulebase file %s: %s", file.Name(), err)
+			continue
+		}
+		revision := ruleBaseRevision(dat)
+		old, ok := loaded[file.Name()]
+		if ok && old.Revision == revision || rejected[file.Name()] == revision {
+			continue
+		}
+		desc, err := parseRuleBaseDescription(dat)
+		if err == nil {
+			desc.Filename = file.Name()
+			if other, exists := c.RuleBases[desc.ID]; exists && other.Filename != desc.Filename {
+				if _, statErr := os.Stat(filepath.Join(c.RuleBaseDir, other.Filename)); statErr == nil {
+					err = fmt.Errorf("Rulebase %s is already loaded from %s", desc.ID, other.Filename)
+				}
+			}
+		}
+		if err == nil {
+			err = c.compile(desc, dat)
+		}
+		if err != nil {
+			log.Printf("Could not reload the rulebase file %s: %s", file.Name(), err)
+			rejected[file.Name()] = revision
+			continue
+		}
+		delete(rejected, file.Name())
+		if ok && old.ID != desc.ID {
+			delete(c.RuleBases, old.ID)
+			c.checker.Theories.Remove(old.ID)
+		}
+		c.RuleBases[desc.ID] = desc
+		log.Printf("Rulebase %s reloaded from %s, revision %s", desc.ID, desc.Filename, desc.Revision)
+	}
+	for id, rb := range c.RuleBases {
+		if !present[rb.Filename] {
+			delete(c.RuleBases, id)
+			c.checker.Theories.Remove(id)
+			log.Printf("Rulebase %s unloaded, %s was deleted", id, rb.Filename)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// removal is the set of indices of the statements removed from a
+// document, in increasing order
+type removal []int
+
+// key identifies the removal in the visited set of the search
+func (r removal) key() string {
+	s := make([]string, len(r))
+	for i, j := range r {
+		s[i] = strconv.Itoa(j)
+	}
+	return strings.Join(s, ",")
+}
+
+func (r removal) has(i int) bool {
+	for _, j := range r {
+		if i == j {
+			return true
+		}
+	}
+	return false
+}
+
+// add returns a copy of r with the index i added
+func (r removal) add(i int) removal {
+	r2 := make(removal, 0, len(r)+1)
+	for _, j := range r {
+		if i != -1 && i < j {
+			r2 = append(r2, i)
+			i = -1
+		}
+		r2 = append(r2, j)
+	}
+	if i != -1 {
+		r2 = append(r2, i)
+	}
+	return r2
+}
+
+// contains returns true iff every index of s is an index of r
+func (r removal) contains(s removal) bool {
+	j := 0
+	for _, i := range r {
+		if j < len(s) && s[j] == i {
+			j++
+		}
+	}
+	return j == len(s)
+}
+
+// apply returns a copy of the document without the removed statements
+func (r removal) apply(d *NormalizedDocument) *NormalizedDocument {
+	d2 := *d
+	d2.Statements = []NormalizedStatement{}
+	for i, s := range d.Statements {
+		if !r.has(i) {
+			d2.Statements = append(d2.Statements, s)
+		}
+	}
+	return &d2
+}
+
+// nextLevel returns the removals with one more statement removed from a
+// document with n statements than the removals of level. Each removal is
+// returned only once, even if it can be reached from several removals of level,
+// and removals containing one of the compliant removals found are pruned,
+// since they would not result in minimal changes.
+func nextLevel(level []removal, n int, found []removal) []removal {
+	visited := make(map[string]bool)
+	next := []removal{}
+	for _, r := range level {
+	candidates:
+		for i := 0; i < n; i++ {
+			if r.has(i) {
+				continue
+			}
+			r2 := r.add(i)
+			k := r2.key()
+			if visited[k] {
+				continue
+			}
+			visited[k] = true
+			for _, f := range found {
+				if r2.contains(f) {
+					continue candidates
+				}
+			}
+			next = append(next, r2)
+		}
+	}
+	return next
+}
+
+// searchSubsets searches breadth-first, by the number of statements removed,
+// for compliant documents with subsets of the statements of doc and pushes them
+// down the out channel. The documents of each level of the search are checked
+// in parallel by the workers of the Search options. The search ends when all
+// subsets have been checked or pruned, when it is cancelled or when the budget
+// of evaluations or time of the Search options is exhausted.
+func (c ComplianceChecker) searchSubsets(theory *Theory, doc *NormalizedDocument, cncl Canceller, out chan<- *NormalizedDocument) {
+	opts := c.Search
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	var deadline <-chan time.Time
+	if opts.Timeout > 0 {
+		timer := time.NewTimer(opts.Timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	n := len(doc.Statements)
+	evaluations := 0
+	found := []removal{}
+	level := nextLevel([]removal{{}}, n, found)
+	for len(level) > 0 {
+		jobs := make(chan removal)
+		results := make(chan removal)
+		var wg sync.WaitGroup
+		for w := 0; w < opts.Workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for r := range jobs {
+					compliant, _, err := c.IsCompliant(theory, r.apply(doc))
+					if err != nil {
+						log.Printf("Compliance checking error: %v", err)
+					} else if compliant {
+						results <- r
+					}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		// collect the compliant removals of this level and push the
+		// documents down the out channel, until the search is cancelled
+		collected := make(chan []removal)
+		go func() {
+			compliant := []removal{}
+			for r := range results {
+				compliant = append(compliant, r)
+				select {
+				case <-cncl: // cancelled, keep draining the results
+				case out <- r.apply(doc):
+				}
+			}
+			collected <- compliant
+		}()
+
+		stopped := false
+	feed:
+		for _, r := range level {
+			if opts.MaxEvaluations > 0 && evaluations >= opts.MaxEvaluations {
+				stopped = true
+				break
+			}
+			// check for cancellation and the deadline first, since select
+			// chooses randomly among the cases which are ready
+			select {
+			case <-cncl:
+				stopped = true
+				break feed
+			case <-deadline:
+				stopped = true
+				break feed
+			default:
+			}
+			select {
+			case <-cncl:
+				stopped = true
+				break feed
+			case <-deadline:
+				stopped = true
+				break feed
+			case jobs <- r:
+				evaluations++
+			}
+		}
+		close(jobs)
+		found = append(found, <-collected...)
+		if stopped {
+			return
+		}
+		level = nextLevel(level, n, found)
+	}
+}
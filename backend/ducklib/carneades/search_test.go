@@ -0,0 +1,219 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+)
+
+// testRuleBase requires consent for data use statements with the action
+// market and for statements with the action share, if the document also
+// has a statement with the action collect
+const testRuleBase = `
+meta:
+  title: Test Rulebase
+  id: test
+  explanation_predicates:
+    - predicate: consentRequired
+      default: true
+
+language:
+  dataUseStatement/1: dataUseStatement(%s)
+  consentRequired/1: consentRequired(%s)
+  docConsentRequired/0: docConsentRequired
+  notDocConsentRequired/0: notDocConsentRequired
+
+issue_schemes:
+  docConsent: [docConsentRequired, notDocConsentRequired]
+
+argument_schemes:
+  - id: market
+    meta:
+      title: Marketing
+      notes: Marketing requires consent
+    variables: [US,USL,Q,DC,SS,SSL,RS,RSL,ID,P,PA]
+    premises:
+      - dataUseStatement(dus(US,USL,Q,DC,SS,SSL,market,RS,RSL,ID,P,PA))
+    conclusions:
+      - consentRequired(dus(US,USL,Q,DC,SS,SSL,market,RS,RSL,ID,P,PA))
+
+  - id: share
+    variables: [US,USL,Q,DC,SS,SSL,RS,RSL,ID,P,PA,US2,USL2,Q2,DC2,SS2,SSL2,RS2,RSL2,ID2,P2,PA2]
+    premises:
+      - dataUseStatement(dus(US,USL,Q,DC,SS,SSL,share,RS,RSL,ID,P,PA))
+      - dataUseStatement(dus(US2,USL2,Q2,DC2,SS2,SSL2,collect,RS2,RSL2,ID2,P2,PA2))
+    conclusions:
+      - consentRequired(dus(US,USL,Q,DC,SS,SSL,share,RS,RSL,ID,P,PA))
+
+  - id: docConsent1
+    weight:
+       constant: 0.1
+    conclusions:
+      - notDocConsentRequired
+
+  - id: docConsent2
+    variables: [S]
+    premises:
+      - consentRequired(S)
+    conclusions:
+      - docConsentRequired
+`
+
+func testTheory(t *testing.T) *Theory {
+	theory, err := MakeComplianceChecker().GetTheory("test", "test", strings.NewReader(testRuleBase))
+	if err != nil {
+		t.Fatalf("GetTheory() error = %v", err)
+	}
+	return theory
+}
+
+// actionDoc returns a document with a statement for each action,
+// with the tracking ids s0, s1, ...
+func actionDoc(actions ...string) *NormalizedDocument {
+	d := &NormalizedDocument{IsA: map[string]string{}}
+	for i, a := range actions {
+		d.Statements = append(d.Statements, NormalizedStatement{
+			Statement: structs.Statement{
+				UseScopeCode:     "capability",
+				QualifierCode:    "identified_data",
+				DataCategoryCode: "customer_content",
+				SourceScopeCode:  "capability",
+				ActionCode:       a,
+				ResultScopeCode:  "capability",
+				TrackingID:       "s" + string('0'+rune(i)),
+			},
+			UseScopeLocation:    "null",
+			SourceScopeLocation: "null",
+			ResultScopeLocation: "null",
+		})
+	}
+	return d
+}
+
+func Test_removal(t *testing.T) {
+	r := removal{1, 3}
+	if got := r.add(0); !reflect.DeepEqual(got, removal{0, 1, 3}) {
+		t.Errorf("removal.add() = %v, want %v", got, removal{0, 1, 3})
+	}
+	if got := r.add(2); !reflect.DeepEqual(got, removal{1, 2, 3}) {
+		t.Errorf("removal.add() = %v, want %v", got, removal{1, 2, 3})
+	}
+	if got := r.add(4); !reflect.DeepEqual(got, removal{1, 3, 4}) {
+		t.Errorf("removal.add() = %v, want %v", got, removal{1, 3, 4})
+	}
+	if !reflect.DeepEqual(r, removal{1, 3}) {
+		t.Errorf("removal.add() modified the removal: %v", r)
+	}
+
+	tests := []struct {
+		name string
+		r    removal
+		s    removal
+		want bool
+	}{
+		{"empty", removal{1}, removal{}, true},
+		{"equal", removal{1, 3}, removal{1, 3}, true},
+		{"superset", removal{0, 1, 2, 3}, removal{1, 3}, true},
+		{"subset", removal{1}, removal{1, 3}, false},
+		{"disjoint", removal{0, 2}, removal{1, 3}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.r.contains(tt.s); got != tt.want {
+			t.Errorf("%q. removal.contains() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func Test_nextLevel(t *testing.T) {
+	tests := []struct {
+		name  string
+		level []removal
+		found []removal
+		want  []string
+	}{
+		{"first level", []removal{{}}, nil, []string{"0", "1", "2"}},
+		{"deduplicated", []removal{{0}, {1}, {2}}, nil, []string{"0,1", "0,2", "1,2"}},
+		{"pruned", []removal{{0}, {2}}, []removal{{1}}, []string{"0,2"}},
+		{"last level", []removal{{0, 1}, {0, 2}, {1, 2}}, nil, []string{"0,1,2"}},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for _, r := range nextLevel(tt.level, 3, tt.found) {
+			got = append(got, r.key())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q. nextLevel() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestComplianceChecker_CompliantDocuments(t *testing.T) {
+	theory := testTheory(t)
+	tests := []struct {
+		name string
+		doc  *NormalizedDocument
+		opts SearchOptions
+		want []string
+	}{
+		{"compliant", actionDoc("provide", "improve"), DefaultSearchOptions, nil},
+		{"one statement", actionDoc("provide", "market"), SearchOptions{Workers: 2}, []string{"s0"}},
+		{"pair", actionDoc("share", "provide", "collect"), SearchOptions{Workers: 2}, []string{"s0,s1", "s1,s2"}},
+		{"minimal only", actionDoc("market", "share", "collect"), SearchOptions{Workers: 3}, []string{"s1", "s2"}},
+		{"evaluation budget", actionDoc("market", "share", "collect"), SearchOptions{Workers: 1, MaxEvaluations: 3}, []string{}},
+		{"timeout", actionDoc("market", "share", "collect"), SearchOptions{Workers: 1, Timeout: time.Nanosecond}, []string{}},
+	}
+	for _, tt := range tests {
+		c := MakeComplianceChecker()
+		c.Search = tt.opts
+		cncl := MakeCanceller()
+		compliant, docs, err := c.CompliantDocuments(theory, tt.doc, cncl)
+		if err != nil {
+			t.Errorf("%q. CompliantDocuments() error = %v", tt.name, err)
+			continue
+		}
+		if compliant != (tt.want == nil) {
+			t.Errorf("%q. CompliantDocuments() compliant = %v, want %v", tt.name, compliant, tt.want == nil)
+		}
+		if compliant {
+			continue
+		}
+		got := []string{}
+		for d := range docs {
+			ids := []string{}
+			for _, s := range d.Statements {
+				ids = append(ids, s.TrackingID)
+			}
+			got = append(got, strings.Join(ids, ","))
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q. CompliantDocuments() = %v, want %v", tt.name, got, tt.want)
+		}
+		cncl.Cancel()
+	}
+}
+
+func TestComplianceChecker_CompliantDocuments_cancel(t *testing.T) {
+	c := MakeComplianceChecker()
+	c.Search = SearchOptions{Workers: 2}
+	cncl := MakeCanceller()
+	_, docs, err := c.CompliantDocuments(testTheory(t), actionDoc("market", "market", "market", "market"), cncl)
+	if err != nil {
+		t.Fatalf("CompliantDocuments() error = %v", err)
+	}
+	cncl.Cancel()
+	select {
+	case <-docs:
+		for range docs {
+		}
+	case <-time.After(10 * time.Second):
+		t.Errorf("CompliantDocuments() did not terminate after being cancelled")
+	}
+}
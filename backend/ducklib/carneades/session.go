@@ -0,0 +1,383 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carneades/carneades-4/src/engine/caes"
+	"github.com/carneades/carneades-4/src/engine/terms"
+)
+
+// DefaultSessionIdle is how long a session is kept after it was last used
+const DefaultSessionIdle = 10 * time.Minute
+
+// SessionResult is the result of checking a document in a session. Changes lists the
+// changes of the explanation since the previous check in the session, Added and Retracted
+// the number of assumptions added to and retracted from the argument graph of the session.
+type SessionResult struct {
+	Compliant   Verdict             `json:"compliant"`
+	Explanation Explanation         `json:"explanation"` // folded, as returned when checking a document
+	Issues      []UnresolvedIssue   `json:"issues,omitempty"`
+	Changes     []ExplanationChange `json:"changes"`
+	Added       int                 `json:"added"`
+	Retracted   int                 `json:"retracted"`
+	Incremental bool                `json:"incremental"` // false if the argument graph was constructed from scratch
+}
+
+// session keeps the argument graph of a revision of a document checked against a theory,
+// so that edits of the document only add or retract the changed assumptions
+type session struct {
+	lock        sync.Mutex
+	theory      *Theory
+	ag          *caes.ArgGraph
+	assumptions map[string]bool // the normalized assumptions of the document
+	explanation Explanation     // the folded explanation of the previous check
+	used        time.Time       // guarded by the lock of the sessionCache
+}
+
+type sessionKey struct {
+	documentID string
+	revision   string
+	ruleBaseID string
+}
+
+// sessionCache keeps the sessions of a ComplianceCheckerPlugin, removing those
+// which were not used for longer than idle
+type sessionCache struct {
+	lock     sync.Mutex
+	idle     time.Duration
+	sessions map[sessionKey]*session
+}
+
+func newSessionCache() *sessionCache {
+	return &sessionCache{idle: DefaultSessionIdle, sessions: make(map[sessionKey]*session)}
+}
+
+// get returns the session with the key, creating it if there is none,
+// and removes the idle sessions
+func (c *sessionCache) get(key sessionKey) *session {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := time.Now()
+	for k, s := range c.sessions {
+		if k != key && now.Sub(s.used) > c.idle {
+			delete(c.sessions, k)
+		}
+	}
+	s, ok := c.sessions[key]
+	if !ok {
+		s = &session{}
+		c.sessions[key] = s
+	}
+	s.used = now
+	return s
+}
+
+func (c *sessionCache) len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.sessions)
+}
+
+// check checks the document against the theory, reusing the argument graph of the
+// previous check if it was constructed with the same theory. The graph is constructed
+// from scratch if the theory deletes statements, see incremental.
+func (s *session) check(checker *ComplianceChecker, theory *Theory, document *NormalizedDocument) (*SessionResult, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	seed := assumeDocument(theory, document)
+	assumptions := make(map[string]bool, len(seed.Assumptions))
+	for _, a := range seed.Assumptions {
+		assumptions[terms.Normalize(a)] = true
+	}
+
+	result := &SessionResult{}
+	if s.ag == nil || s.theory != theory || !incremental(theory) {
+		if err := checker.inferAndLabel(seed); err != nil {
+			s.ag = nil
+			return nil, err
+		}
+		s.theory, s.ag = theory, seed
+		result.Added = len(assumptions)
+	} else {
+		retracted := []string{}
+		for a := range s.assumptions {
+			if !assumptions[a] {
+				retracted = append(retracted, a)
+			}
+		}
+		// the assumptions of schemes are dropped as well, they are added again by the
+		// schemes which are still instantiated, see infer
+		kept := []string{}
+		for _, a := range s.ag.Assumptions {
+			if assumptions[a] {
+				kept = append(kept, a)
+			}
+		}
+		s.ag.Assumptions = kept
+		retract(s.ag, retracted)
+		result.Retracted = len(retracted)
+
+		for id, stmt := range seed.Statements {
+			a := terms.Normalize(id)
+			old, ok := s.ag.Statements[a]
+			if !ok {
+				s.ag.Statements[a] = stmt
+			} else if setID, ok := stmt.Metadata[assumptionSetKey]; ok {
+				// the fact may have been moved to another assumption set
+				if old.Metadata == nil {
+					old.Metadata = make(map[string]interface{})
+				}
+				old.Metadata[assumptionSetKey] = setID
+			} else {
+				delete(old.Metadata, assumptionSetKey)
+			}
+			if !s.assumptions[a] {
+				s.ag.Assumptions = append(s.ag.Assumptions, a)
+				result.Added++
+			}
+		}
+		if result.Added > 0 || result.Retracted > 0 {
+			if err := s.infer(checker); err != nil {
+				s.ag = nil
+				return nil, err
+			}
+		}
+		result.Incremental = true
+	}
+	s.assumptions = assumptions
+
+	v, issues, err := verdict(s.ag)
+	if err != nil {
+		return nil, err
+	}
+	e, err := checker.GetExplanation(theory, s.ag)
+	if err != nil {
+		return nil, err
+	}
+	folded := FoldExplanation(e)
+	result.Compliant, result.Issues, result.Explanation = v, issues, folded
+	result.Changes = explanationChanges(s.explanation, folded)
+	s.explanation = folded
+	return result, nil
+}
+
+// infer runs inference on the argument graph of the session again and labels it. Inference
+// is seeded with the arguments of the graph, but does not recognize them among the results,
+// which print lists differently, so the arguments are instantiated again, adding the
+// assumptions of their schemes. These duplicates are removed.
+func (s *session) infer(checker *ComplianceChecker) error {
+	ag := s.ag
+	prev := make(map[string]bool, len(ag.Arguments))
+	known := make(map[string]bool, len(ag.Arguments))
+	for id, arg := range ag.Arguments {
+		prev[id] = true
+		if arg.Scheme != nil {
+			known[argumentKey(arg)] = true
+		}
+	}
+	if err := checker.infer(ag); err != nil {
+		return err
+	}
+	for id, arg := range ag.Arguments {
+		if !prev[id] && arg.Scheme != nil && known[argumentKey(arg)] {
+			removeArgument(ag, arg)
+		}
+	}
+	// Infer marks the statements matching an existing issue without adding them to its positions
+	for _, stmt := range ag.Statements {
+		if stmt.Issue != nil && !isPosition(stmt.Issue, stmt) {
+			stmt.Issue.Positions = append(stmt.Issue.Positions, stmt)
+		}
+	}
+	ag.ApplyLabelling(ag.GroundedLabelling())
+	return nil
+}
+
+// incremental reports whether the argument graph of the theory can be updated incrementally,
+// i.e. no scheme deletes statements. Which statements the CHR rules of such schemes delete,
+// e.g. one of compatiblePurpose(S1,S2) and compatiblePurpose(S2,S1) as duplicates, depends
+// on the order in which the statements are derived, and the arguments of the graph seeding
+// the inference change this order, so the result would depend on the previous revisions.
+func incremental(theory *Theory) bool {
+	for _, scheme := range theory.ArgSchemes {
+		if len(scheme.Deletions) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// argumentKey identifies the instantiation of a scheme by an argument
+func argumentKey(arg *caes.Argument) string {
+	return terms.Normalize("argument(" + arg.Scheme.Id + ",[" + strings.Join(arg.Parameters, ",") + "])")
+}
+
+// retract removes the statements with the given ids from the graph, together with the
+// arguments and statements which are no longer derived from the assumptions of the graph,
+// so that the graph is the one which would have been constructed without them. Statements
+// without arguments are the assumptions of the graph and of the schemes, the latter are
+// kept as long as they are used. The derived statements are the least fixpoint, so that
+// statements supporting each other in a cycle, e.g. transitive relations, are retracted too.
+func retract(ag *caes.ArgGraph, ids []string) {
+	assumed := make(map[string]bool, len(ag.Assumptions))
+	for _, a := range ag.Assumptions {
+		assumed[a] = true
+	}
+	retracted := make(map[*caes.Statement]bool)
+	for _, id := range ids {
+		if stmt, ok := ag.Statements[id]; ok {
+			retracted[stmt] = true
+		}
+	}
+	derived := make(map[*caes.Statement]bool)
+	for _, stmt := range ag.Statements {
+		if len(stmt.Args) == 0 && !retracted[stmt] {
+			derived[stmt] = true
+		}
+	}
+	applicable := func(arg *caes.Argument) bool {
+		for _, p := range arg.Premises {
+			if !derived[p.Stmt] {
+				return false
+			}
+		}
+		return true
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, stmt := range ag.Statements {
+			if derived[stmt] {
+				continue
+			}
+			for _, arg := range stmt.Args {
+				if applicable(arg) {
+					derived[stmt] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	for _, arg := range ag.Arguments {
+		if _, ok := ag.Arguments[arg.Id]; ok && !applicable(arg) {
+			removeArgument(ag, arg)
+		}
+	}
+	used := make(map[*caes.Statement]bool)
+	for _, arg := range ag.Arguments {
+		for _, p := range arg.Premises {
+			used[p.Stmt] = true
+		}
+		if arg.Undercutter != nil {
+			used[arg.Undercutter] = true
+		}
+	}
+	for id, stmt := range ag.Statements {
+		if !used[stmt] && (!derived[stmt] || len(stmt.Args) == 0 && !assumed[id]) {
+			delete(ag.Statements, id)
+			removePosition(ag, stmt)
+		}
+	}
+}
+
+// removeArgument removes an argument from the graph, with its undercutter and the
+// arguments for the exceptions of its scheme. The premises of the removed arguments
+// are returned.
+func removeArgument(ag *caes.ArgGraph, arg *caes.Argument) []*caes.Statement {
+	stmts := []*caes.Statement{}
+	remove := func(arg *caes.Argument) {
+		delete(ag.Arguments, arg.Id)
+		if c := arg.Conclusion; c != nil {
+			for i, a := range c.Args {
+				if a == arg {
+					c.Args = append(c.Args[:i], c.Args[i+1:]...)
+					break
+				}
+			}
+		}
+		for _, p := range arg.Premises {
+			stmts = append(stmts, p.Stmt)
+		}
+	}
+	remove(arg)
+	if uc := arg.Undercutter; uc != nil {
+		// the undercutter is shared by the arguments of the other conclusions of the scheme
+		for _, a := range ag.Arguments {
+			if a.Undercutter == uc {
+				return stmts
+			}
+		}
+		delete(ag.Statements, terms.Normalize(uc.Id))
+		for _, e := range uc.Args {
+			remove(e)
+			if e.Undercutter != nil {
+				delete(ag.Statements, terms.Normalize(e.Undercutter.Id))
+			}
+		}
+	}
+	return stmts
+}
+
+// removePosition removes a statement from the positions of its issue,
+// removing the issue if less than two positions are left
+func removePosition(ag *caes.ArgGraph, stmt *caes.Statement) {
+	issue := stmt.Issue
+	if issue == nil {
+		return
+	}
+	stmt.Issue = nil
+	positions := []*caes.Statement{}
+	for _, p := range issue.Positions {
+		if p != stmt {
+			positions = append(positions, p)
+		}
+	}
+	issue.Positions = positions
+	if len(positions) < 2 {
+		delete(ag.Issues, issue.Id)
+		for _, p := range positions {
+			p.Issue = nil
+		}
+	}
+}
+
+func isPosition(issue *caes.Issue, stmt *caes.Statement) bool {
+	for _, p := range issue.Positions {
+		if p == stmt {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckInSession checks a revision of a document against the rulebase with the given id in
+// a session, reusing the argument graph of the previous check of the same revision. Only the
+// assumptions of statements which were added or changed since the previous check are added
+// to the graph and those of removed or changed statements retracted, before inference and
+// labelling are run again. The graph is constructed from scratch instead if the rulebase has
+// rules with deletions, so that the result never depends on the previous checks. The result includes the changes of the explanation since the
+// previous check. Sessions are removed after they were not used for SetSessionIdle.
+func (c *ComplianceCheckerPlugin) CheckInSession(ruleBaseID string, documentID string, revision string, document *NormalizedDocument) (*SessionResult, error) {
+	theory, err := c.theory(ruleBaseID)
+	if err != nil {
+		return nil, err
+	}
+	s := c.sessions.get(sessionKey{documentID, revision, ruleBaseID})
+	return s.check(c.checker, theory, document)
+}
+
+// SetSessionIdle sets how long sessions are kept after they were last used,
+// by default DefaultSessionIdle
+func (c *ComplianceCheckerPlugin) SetSessionIdle(idle time.Duration) {
+	c.sessions.lock.Lock()
+	defer c.sessions.lock.Unlock()
+	c.sessions.idle = idle
+}
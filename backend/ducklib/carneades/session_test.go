@@ -0,0 +1,210 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/taxonomy"
+)
+
+func TestComplianceCheckerPlugin_CheckInSession(t *testing.T) {
+	c, dir := testPlugin(t)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name            string
+		doc             *NormalizedDocument
+		wantIncremental bool
+		wantAdded       int
+		wantRetracted   int
+	}{
+		{"first check", actionDoc("provide"), false, 1, 0},
+		{"add statement", actionDoc("provide", "market"), true, 1, 0},
+		{"unchanged", actionDoc("provide", "market"), true, 0, 0},
+		{"edit statement", actionDoc("provide", "collect"), true, 1, 1},
+		{"remove statement", actionDoc("provide"), true, 0, 1},
+		{"edit back", actionDoc("provide", "market"), true, 1, 0},
+		{"remove all", actionDoc(), true, 0, 2},
+	}
+	for _, tt := range tests {
+		got, err := c.CheckInSession("test", "doc", "1", tt.doc)
+		if err != nil {
+			t.Fatalf("%q. CheckInSession() error = %v", tt.name, err)
+		}
+		want, err := c.Check("test", tt.doc)
+		if err != nil {
+			t.Fatalf("%q. Check() error = %v", tt.name, err)
+		}
+		if got.Compliant != want.Verdict {
+			t.Errorf("%q. CheckInSession() verdict = %v, want %v", tt.name, got.Compliant, want.Verdict)
+		}
+		// the ids of the arguments depend on the order in which they were constructed
+		if g, w := withoutArgumentIDs(got.Explanation), withoutArgumentIDs(FoldExplanation(want.Explanation)); !reflect.DeepEqual(g, w) {
+			t.Errorf("%q. CheckInSession() explanation = %v, want %v", tt.name, g, w)
+		}
+		if got.Incremental != tt.wantIncremental || got.Added != tt.wantAdded || got.Retracted != tt.wantRetracted {
+			t.Errorf("%q. CheckInSession() incremental, added, retracted = %v, %v, %v, want %v, %v, %v", tt.name,
+				got.Incremental, got.Added, got.Retracted, tt.wantIncremental, tt.wantAdded, tt.wantRetracted)
+		}
+	}
+}
+
+func withoutArgumentIDs(e Explanation) Explanation {
+	for _, se := range e {
+		for p, v := range se {
+			for i := range v.Arguments {
+				v.Arguments[i].ID = ""
+			}
+			se[p] = v
+		}
+	}
+	return e
+}
+
+func TestComplianceCheckerPlugin_CheckInSession_changes(t *testing.T) {
+	c, dir := testPlugin(t)
+	defer os.RemoveAll(dir)
+
+	if _, err := c.CheckInSession("test", "doc", "1", actionDoc("provide")); err != nil {
+		t.Fatalf("CheckInSession() error = %v", err)
+	}
+	got, err := c.CheckInSession("test", "doc", "1", actionDoc("provide", "market"))
+	if err != nil {
+		t.Fatalf("CheckInSession() error = %v", err)
+	}
+	if len(got.Changes) == 0 {
+		t.Fatalf("CheckInSession() changes = %v, want the explanation of s1", got.Changes)
+	}
+	for _, ch := range got.Changes {
+		if ch.TrackingID != "s1" || ch.Before != nil || ch.After == nil {
+			t.Errorf("CheckInSession() change = %+v, want an addition for s1", ch)
+		}
+	}
+
+	// a changed rulebase is a new theory, whose graph is constructed from scratch
+	if _, err := c.PutRuleBase("test", testRuleBaseWithID("test", false)); err != nil {
+		t.Fatalf("PutRuleBase() error = %v", err)
+	}
+	got, err = c.CheckInSession("test", "doc", "1", actionDoc("provide", "market"))
+	if err != nil {
+		t.Fatalf("CheckInSession() error = %v", err)
+	}
+	want, err := c.Check("test", actionDoc("provide", "market"))
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if got.Incremental || got.Compliant != want.Verdict {
+		t.Errorf("CheckInSession() after PutRuleBase = %v, %v, want %v, %v", got.Incremental, got.Compliant, false, want.Verdict)
+	}
+}
+
+func TestComplianceCheckerPlugin_SetSessionIdle(t *testing.T) {
+	c, dir := testPlugin(t)
+	defer os.RemoveAll(dir)
+
+	for _, rev := range []string{"1", "2"} {
+		if _, err := c.CheckInSession("test", "doc", rev, actionDoc("provide")); err != nil {
+			t.Fatalf("CheckInSession() error = %v", err)
+		}
+	}
+	if got := c.sessions.len(); got != 2 {
+		t.Errorf("sessions = %v, want %v", got, 2)
+	}
+
+	c.SetSessionIdle(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	got, err := c.CheckInSession("test", "doc", "3", actionDoc("provide"))
+	if err != nil {
+		t.Fatalf("CheckInSession() error = %v", err)
+	}
+	if got.Incremental {
+		t.Errorf("CheckInSession() of a new revision is incremental")
+	}
+	if got := c.sessions.len(); got != 1 {
+		t.Errorf("sessions after idle = %v, want %v", got, 1)
+	}
+}
+
+// randomEdit edits a random statement of the document, adding or removing statements
+// at random, and returns the edited copy
+func randomEdit(r *rand.Rand, doc *NormalizedDocument) *NormalizedDocument {
+	pick := func(codes ...string) string { return codes[r.Intn(len(codes))] }
+	d := *doc
+	d.Statements = append([]NormalizedStatement{}, doc.Statements...)
+	switch op := r.Intn(6); {
+	case op == 0 && len(d.Statements) < 5 || len(d.Statements) == 0:
+		s := actionDoc("provide").Statements[0]
+		s.TrackingID = fmt.Sprintf("s%d", r.Intn(10))
+		d.Statements = append(d.Statements, s)
+	case op == 1 && len(d.Statements) > 1:
+		i := r.Intn(len(d.Statements))
+		d.Statements = append(d.Statements[:i], d.Statements[i+1:]...)
+	default:
+		s := &d.Statements[r.Intn(len(d.Statements))]
+		switch r.Intn(5) {
+		case 0:
+			s.ActionCode = pick("provide", "improve", "upgrades", "market", "advertise_contextual", "share", "collect")
+		case 1:
+			s.ResultScopeCode = pick("capability", "service", "services_agreement", "csp_services", "csp_products", "third_party_services", "third_party_partners")
+		case 2:
+			s.QualifierCode = pick("identified_data", "pseudonymized_data", "anonymized_data", "unqualified")
+		case 3:
+			s.DataCategoryCode = pick("customer_content", "account_data", "derived_data_user_location", "provider_data")
+		case 4:
+			s.UseScopeLocation = pick("null", "de", "us", "cn")
+		}
+	}
+	return &d
+}
+
+// TestSession_check_rb2 checks random sequences of edits of a document against rb2 in a
+// session, whose results have to be those of checking each revision from scratch
+func TestSession_check_rb2(t *testing.T) {
+	tax, err := taxonomy.Load(filepath.Join("..", "..", "..", "frontend", "src", "assets", "config"), taxonomy.DefaultLocale)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	c := MakeComplianceChecker()
+	theory := testRb2Theory(t, c)
+	for seed := int64(1); seed <= 4; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		s := &session{}
+		doc := actionDoc("provide", "market")
+		for step := 0; step < 15; step++ {
+			doc = randomEdit(r, doc)
+			doc.Facts = tax.Facts()
+			got, err := s.check(c, theory, doc)
+			if err != nil {
+				t.Fatalf("seed %d, step %d. check() error = %v", seed, step, err)
+			}
+			want, err := c.Check(theory, doc)
+			if err != nil {
+				t.Fatalf("seed %d, step %d. Check() error = %v", seed, step, err)
+			}
+			if got.Compliant != want.Verdict {
+				t.Errorf("seed %d, step %d. check() verdict = %v, want %v", seed, step, got.Compliant, want.Verdict)
+			}
+			// the arguments of duplicate statements may be listed in another order,
+			// so only the values of the explanation predicates are compared
+			w := FoldExplanation(want.Explanation)
+			if len(got.Explanation) != len(w) {
+				t.Errorf("seed %d, step %d. check() explains %d statements, want %d", seed, step, len(got.Explanation), len(w))
+			}
+			for id, preds := range w {
+				for p, v := range preds {
+					if g, ok := got.Explanation[id][p]; !ok || g.Value != v.Value || g.Assumed != v.Assumed {
+						t.Errorf("seed %d, step %d. check() %s of %s = %+v, want %+v", seed, step, p, id, g, v)
+					}
+				}
+			}
+		}
+	}
+}
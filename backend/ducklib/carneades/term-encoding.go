@@ -0,0 +1,101 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+	"github.com/carneades/carneades-4/src/engine/terms"
+)
+
+// escapePrefix starts the atoms of values which cannot be used as atoms themselves
+const escapePrefix = "x__"
+
+// encodeValue returns the atom for a value of a document, such as a code, location or tracking id.
+// Values which are read back as the same atom, i.e. names other than true and false starting
+// with a lowercase letter and made of letters, digits and underscores, are kept, so that the
+// rules of a rulebase can refer to the codes of its dictionary. Any other value, including one
+// starting with the escape prefix, is hex encoded after the prefix, so no two values have the same atom.
+func encodeValue(value string) terms.Atom {
+	if structs.IsAtom(value) && !strings.HasPrefix(value, escapePrefix) {
+		return terms.Atom(value)
+	}
+	return terms.Atom(escapePrefix + hex.EncodeToString([]byte(value)))
+}
+
+// decodeValue returns the value of a document encoded by the term,
+// or the term as a string if it is not an encoded value
+func decodeValue(t terms.Term) string {
+	s := t.String()
+	if t.Type() != terms.AtomType || !strings.HasPrefix(s, escapePrefix) {
+		return s
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(s, escapePrefix))
+	if err != nil {
+		return s
+	}
+	return string(b)
+}
+
+// decodeTerm returns the term as a string with the values of the document it contains
+// decoded, for showing it to users. The string is not necessarily read back as the term.
+func decodeTerm(t terms.Term) string {
+	switch t := t.(type) {
+	case terms.Atom:
+		return decodeValue(t)
+	case terms.Compound:
+		args := make([]string, len(t.Args))
+		for i, arg := range t.Args {
+			args[i] = decodeTerm(arg)
+		}
+		return t.Functor + "(" + strings.Join(args, ",") + ")"
+	case terms.List:
+		elems := make([]string, len(t))
+		for i, e := range t {
+			elems[i] = decodeTerm(e)
+		}
+		return "[" + strings.Join(elems, ",") + "]"
+	default:
+		return t.String()
+	}
+}
+
+// decodeStatement returns the id of a statement of an argument graph with the values of
+// the document it contains decoded, see decodeTerm
+func decodeStatement(id string) string {
+	t, ok := terms.ReadString(id)
+	if !ok {
+		return id
+	}
+	return decodeTerm(t)
+}
+
+// dusTerm constructs the data use statement term of a statement,
+// dataUseStatement(dus(UseScope,UseScopeLocation,Qualifier,DataCategory,SourceScope,
+// SourceScopeLocation,Action,ResultScope,ResultScopeLocation,TrackingID,PlaceInStruct,Passive))
+func dusTerm(s NormalizedStatement) terms.Compound {
+	dus := terms.Compound{Functor: "dus", Args: []terms.Term{
+		encodeValue(s.UseScopeCode),
+		encodeValue(s.UseScopeLocation),
+		encodeValue(s.QualifierCode),
+		encodeValue(s.DataCategoryCode),
+		encodeValue(s.SourceScopeCode),
+		encodeValue(s.SourceScopeLocation),
+		encodeValue(s.ActionCode),
+		encodeValue(s.ResultScopeCode),
+		encodeValue(s.ResultScopeLocation),
+		encodeValue(s.TrackingID),
+		terms.Int(s.PlaceInStruct),
+		terms.Bool(s.Passive),
+	}}
+	return terms.Compound{Functor: "dataUseStatement", Args: []terms.Term{dus}}
+}
+
+// isATerm constructs the term isA(Thing,Capability) of an is a relationship of a document
+func isATerm(thing, capability string) terms.Compound {
+	return terms.Compound{Functor: "isA", Args: []terms.Term{encodeValue(thing), encodeValue(capability)}}
+}
@@ -0,0 +1,172 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"os"
+	"testing"
+
+	"github.com/carneades/carneades-4/src/engine/terms"
+	chrparser "github.com/hfried/GoCHR/src/engine/parser"
+)
+
+var encodingValues = []string{
+	"capability", "eu", "s0", "null", "",
+	"EU", "Capability", "a,b", "f(x)", "it's", `say "hi"`, "two words",
+	"x__6162", "x__", "true", "false", "mod", "_x", "1st", "über", "a.b", "[X|Y]",
+	"in", "is", "or", "and", "not",
+}
+
+func TestEncodeValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  terms.Atom
+	}{
+		{"capability", "capability"},
+		{"identified_data", "identified_data"},
+		{"s0", "s0"},
+		{"EU", "x__4555"},
+		{"a,b", "x__612c62"},
+		{"x__", "x__785f5f"},
+		{"true", "x__74727565"},
+		{"", "x__"},
+		// location codes of India and Iceland, which the parsers read as atoms
+		{"in", "in"},
+		{"is", "is"},
+		{"mod", "mod"},
+	}
+	for _, tt := range tests {
+		if got := encodeValue(tt.value); got != tt.want {
+			t.Errorf("encodeValue(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// roundTrip checks that the value is encoded as an atom which both the
+// term parser and the parser of the inference engine read back as an atom
+func roundTrip(t *testing.T, value string) {
+	a := encodeValue(value)
+	got, ok := terms.ReadString("f(" + string(a) + ")")
+	if !ok {
+		t.Fatalf("ReadString() of %q failed", a)
+	}
+	c, ok := got.(terms.Compound)
+	if !ok || len(c.Args) != 1 || c.Args[0].Type() != terms.AtomType {
+		t.Fatalf("ReadString() of %q = %v, want an atom", a, got)
+	}
+	if v := decodeValue(c.Args[0]); v != value {
+		t.Errorf("decodeValue(encodeValue(%q)) = %q", value, v)
+	}
+	if chr, ok := chrparser.ReadString("f(" + string(a) + ")"); !ok || chr.String() != "f("+string(a)+")" {
+		t.Errorf("inference engine reads %q as %v", a, chr)
+	}
+}
+
+func TestEncodeValue_roundTrip(t *testing.T) {
+	for _, v := range encodingValues {
+		roundTrip(t, v)
+	}
+}
+
+func FuzzEncodeValue(f *testing.F) {
+	for _, v := range encodingValues {
+		f.Add(v)
+	}
+	f.Fuzz(roundTrip)
+}
+
+func TestDusTerm_roundTrip(t *testing.T) {
+	doc := actionDoc("provide")
+	s := doc.Statements[0]
+	s.TrackingID = `Statement 1, "(X)"`
+	s.UseScopeLocation = "EU"
+	s.PlaceInStruct = 3
+	s.Passive = true
+
+	got, ok := terms.ReadString(dusTerm(s).String())
+	if !ok {
+		t.Fatalf("ReadString() of %v failed", dusTerm(s))
+	}
+	dus := got.(terms.Compound).Args[0].(terms.Compound)
+	if len(dus.Args) != 12 {
+		t.Fatalf("dus has %v arguments, want %v", len(dus.Args), 12)
+	}
+	if id := stmtID(dus); id != s.TrackingID {
+		t.Errorf("stmtID() = %q, want %q", id, s.TrackingID)
+	}
+	if loc := decodeValue(dus.Args[1]); loc != s.UseScopeLocation {
+		t.Errorf("location = %q, want %q", loc, s.UseScopeLocation)
+	}
+	if dus.Args[10] != terms.Int(3) || dus.Args[11] != terms.Bool(true) {
+		t.Errorf("place in struct, passive = %v, %v, want %v, %v", dus.Args[10], dus.Args[11], 3, true)
+	}
+}
+
+func TestComplianceCheckerPlugin_Check_unsafeTrackingIDs(t *testing.T) {
+	c, dir := testPlugin(t)
+	defer os.RemoveAll(dir)
+
+	want, err := c.Check("test", actionDoc("provide", "market"))
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	doc := actionDoc("provide", "market")
+	ids := []string{"Statement 1", `s0,"(X)')`}
+	for i, id := range ids {
+		doc.Statements[i].TrackingID = id
+	}
+	got, err := c.Check("test", doc)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if got.Verdict != want.Verdict {
+		t.Errorf("Check() verdict = %v, want %v", got.Verdict, want.Verdict)
+	}
+	for i, id := range ids {
+		if _, ok := got.Explanation[id]; !ok {
+			t.Errorf("Check() explanation has no statement %q", id)
+		}
+		w := want.Explanation["s"+string('0'+rune(i))]
+		if len(got.Explanation[id]) != len(w) {
+			t.Errorf("Check() explanation of %q = %v, want %v", id, got.Explanation[id], w)
+		}
+	}
+}
+
+func TestDusTerm_locationCodes(t *testing.T) {
+	for _, loc := range []string{"in", "is", "de"} {
+		s := actionDoc("provide").Statements[0]
+		s.UseScopeLocation = loc
+		src := dusTerm(s).String()
+
+		// rules of a rulebase refer to the location codes as atoms
+		got, ok := terms.ReadString(src)
+		if !ok {
+			t.Fatalf("ReadString() of %v failed", src)
+		}
+		if arg := got.(terms.Compound).Args[0].(terms.Compound).Args[1]; arg != terms.Atom(loc) {
+			t.Errorf("location of %v = %v, want the atom %v", src, arg, loc)
+		}
+		if chr, ok := chrparser.ReadString(src); !ok || chr.String() != src {
+			t.Errorf("inference engine reads %v as %v", src, chr)
+		}
+	}
+}
+
+func Test_decodeStatement(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"docConsentRequired", "docConsentRequired"},
+		{"isInEu(" + string(encodeValue("S-1")) + ")", "isInEu(S-1)"},
+		{"p([" + string(encodeValue("a b")) + ",c],X)", "p([a b,c],X)"},
+		{"not a term(", "not a term("},
+	}
+	for _, tt := range tests {
+		if got := decodeStatement(tt.id); got != tt.want {
+			t.Errorf("decodeStatement(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
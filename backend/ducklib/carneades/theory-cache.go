@@ -0,0 +1,129 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+)
+
+// DefaultTheoryCacheSize is the maximum number of theories cached by a new ComplianceChecker
+const DefaultTheoryCacheSize = 32
+
+// TheoryCache is a cache of compiled theories, indexed by rulebase id, which is
+// safe for concurrent use. At most one theory is cached per rulebase, the theory of
+// the revision compiled last. Concurrent requests for the same revision of a rulebase
+// share a single compilation. When more than the maximum number of theories are
+// cached, the least recently used theory is evicted.
+type TheoryCache struct {
+	lock     sync.Mutex
+	max      int
+	entries  map[string]*list.Element // rulebase id -> element of lru, with a *cacheEntry value
+	lru      *list.List               // most recently used first
+	inflight map[string]*compilation  // rulebase id and revision -> compilation
+	evicted  func(*Theory)            // called with the lock held when a theory leaves the cache, if not nil
+}
+
+type cacheEntry struct {
+	ruleBaseID string
+	VersionedTheory
+}
+
+// compilation is a compilation in progress. done is closed when it has finished.
+type compilation struct {
+	done   chan struct{}
+	theory *Theory
+	err    error
+}
+
+// NewTheoryCache returns an empty cache for at most max theories.
+// If max is less than 1, the size of the cache is not bounded.
+func NewTheoryCache(max int) *TheoryCache {
+	return &TheoryCache{
+		max:      max,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		inflight: make(map[string]*compilation),
+	}
+}
+
+// Get returns the theory of the given revision of the rulebase. If this revision
+// is not cached, the theory is compiled with compile and cached, unless compile
+// returns an error. If the revision is already being compiled, Get waits for the
+// compilation to finish and returns its result.
+func (tc *TheoryCache) Get(ruleBaseID string, revision string, compile func() (*Theory, error)) (*Theory, error) {
+	tc.lock.Lock()
+	if e, ok := tc.entries[ruleBaseID]; ok && e.Value.(*cacheEntry).revision == revision {
+		tc.lru.MoveToFront(e)
+		theory := e.Value.(*cacheEntry).theory
+		tc.lock.Unlock()
+		return theory, nil
+	}
+	key := ruleBaseID + "\x00" + revision
+	if c, ok := tc.inflight[key]; ok {
+		tc.lock.Unlock()
+		<-c.done
+		return c.theory, c.err
+	}
+	// the error is returned to the waiting callers if compile panics
+	c := &compilation{done: make(chan struct{}), err: errors.New("The compilation of the rulebase failed")}
+	tc.inflight[key] = c
+	tc.lock.Unlock()
+
+	defer func() {
+		tc.lock.Lock()
+		delete(tc.inflight, key)
+		if c.err == nil {
+			tc.put(ruleBaseID, VersionedTheory{revision, c.theory})
+		}
+		tc.lock.Unlock()
+		close(c.done)
+	}()
+	c.theory, c.err = compile()
+	return c.theory, c.err
+}
+
+// put caches the theory, evicting the least recently used theories if the
+// cache is full. The caller must hold the lock.
+func (tc *TheoryCache) put(ruleBaseID string, vt VersionedTheory) {
+	if e, ok := tc.entries[ruleBaseID]; ok {
+		tc.evict(e.Value.(*cacheEntry).theory)
+		e.Value.(*cacheEntry).VersionedTheory = vt
+		tc.lru.MoveToFront(e)
+		return
+	}
+	tc.entries[ruleBaseID] = tc.lru.PushFront(&cacheEntry{ruleBaseID, vt})
+	for tc.max > 0 && tc.lru.Len() > tc.max {
+		e := tc.lru.Back()
+		tc.lru.Remove(e)
+		delete(tc.entries, e.Value.(*cacheEntry).ruleBaseID)
+		tc.evict(e.Value.(*cacheEntry).theory)
+	}
+}
+
+func (tc *TheoryCache) evict(theory *Theory) {
+	if tc.evicted != nil {
+		tc.evicted(theory)
+	}
+}
+
+// Remove removes the theory of the rulebase from the cache
+func (tc *TheoryCache) Remove(ruleBaseID string) {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+	if e, ok := tc.entries[ruleBaseID]; ok {
+		tc.lru.Remove(e)
+		delete(tc.entries, ruleBaseID)
+		tc.evict(e.Value.(*cacheEntry).theory)
+	}
+}
+
+// Len returns the number of cached theories
+func (tc *TheoryCache) Len() int {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+	return tc.lru.Len()
+}
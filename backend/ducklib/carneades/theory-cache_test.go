@@ -0,0 +1,159 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTheoryCache_Get(t *testing.T) {
+	tc := NewTheoryCache(2)
+	evicted := 0
+	tc.evicted = func(*Theory) { evicted++ }
+	compiled := 0
+	compile := func() (*Theory, error) {
+		compiled++
+		return &Theory{}, nil
+	}
+	tests := []struct {
+		name         string
+		ruleBaseID   string
+		revision     string
+		wantCompiled int
+	}{
+		{"compile a", "a", "1", 1},
+		{"cached a", "a", "1", 1},
+		{"compile b", "b", "1", 2},
+		{"new revision of a", "a", "2", 3},
+		{"cached new revision of a", "a", "2", 3},
+		{"compile c, evicts b", "c", "1", 4},
+		{"cached a", "a", "2", 4},
+		{"recompile b", "b", "1", 5},
+	}
+	for _, tt := range tests {
+		if _, err := tc.Get(tt.ruleBaseID, tt.revision, compile); err != nil {
+			t.Errorf("%q. TheoryCache.Get() error = %v", tt.name, err)
+		}
+		if compiled != tt.wantCompiled {
+			t.Errorf("%q. TheoryCache.Get() compiled %d times, want %d", tt.name, compiled, tt.wantCompiled)
+		}
+	}
+	if tc.Len() != 2 {
+		t.Errorf("TheoryCache.Len() = %d, want 2", tc.Len())
+	}
+	tc.Remove("b")
+	if tc.Len() != 1 {
+		t.Errorf("TheoryCache.Len() = %d after Remove(), want 1", tc.Len())
+	}
+	if evicted != 4 {
+		t.Errorf("TheoryCache evicted %d theories, want 4", evicted)
+	}
+}
+
+func TestTheoryCache_singleFlight(t *testing.T) {
+	tc := NewTheoryCache(DefaultTheoryCacheSize)
+	var compiled int32
+	release := make(chan struct{})
+	compile := func() (*Theory, error) {
+		atomic.AddInt32(&compiled, 1)
+		<-release
+		return &Theory{}, nil
+	}
+	var wg sync.WaitGroup
+	theories := make([]*Theory, 20)
+	for i := range theories {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			theories[i], _ = tc.Get("a", "1", compile)
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if compiled != 1 {
+		t.Errorf("TheoryCache.Get() compiled %d times, want 1", compiled)
+	}
+	for i, th := range theories {
+		if th != theories[0] {
+			t.Errorf("TheoryCache.Get() returned a different theory to caller %d", i)
+		}
+	}
+}
+
+func TestTheoryCache_panic(t *testing.T) {
+	tc := NewTheoryCache(DefaultTheoryCacheSize)
+	func() {
+		defer func() { recover() }()
+		tc.Get("a", "1", func() (*Theory, error) { panic("bad rulebase") })
+	}()
+	done := make(chan error)
+	go func() {
+		_, err := tc.Get("a", "1", func() (*Theory, error) { return &Theory{}, nil })
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("TheoryCache.Get() error = %v after a panicking compilation", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("TheoryCache.Get() blocked after a panicking compilation")
+	}
+}
+
+// TestComplianceChecker_IsCompliant_concurrent should be run with go test -race
+func TestComplianceChecker_IsCompliant_concurrent(t *testing.T) {
+	c := MakeComplianceChecker()
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				// alternate between two revisions to force recompilations
+				theory, err := c.GetTheory("test", string('a'+rune((i+j)%2)), strings.NewReader(testRuleBase))
+				if err != nil {
+					t.Errorf("GetTheory() error = %v", err)
+					return
+				}
+				compliant, _, err := c.IsCompliant(theory, actionDoc("provide", "market"))
+				if err != nil || compliant {
+					t.Errorf("IsCompliant() = %v, %v, want false", compliant, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// TestComplianceCheckerPlugin_IsCompliant_concurrent checks documents while the
+// rulebase is replaced. It should be run with go test -race.
+func TestComplianceCheckerPlugin_IsCompliant_concurrent(t *testing.T) {
+	c, dir := testPlugin(t)
+	defer os.RemoveAll(dir)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				if _, _, err := c.IsCompliant("test", actionDoc("provide", "market")); err != nil {
+					t.Errorf("IsCompliant() error = %v", err)
+				}
+			}
+		}()
+	}
+	for j := 0; j < 5; j++ {
+		if _, err := c.PutRuleBase("test", testRuleBaseWithID("test", j%2 == 0)); err != nil {
+			t.Errorf("PutRuleBase() error = %v", err)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,107 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+
+package carneades
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/carneades/carneades-4/src/engine/caes"
+)
+
+// Verdict is the result of checking a document against a rulebase, derived from
+// the label of the statement that the document does not require consent
+type Verdict string
+
+const (
+	// Compliant documents do not require consent, the statement is in
+	Compliant Verdict = "COMPLIANT"
+	// NonCompliant documents require consent, the statement is out
+	NonCompliant Verdict = "NON_COMPLIANT"
+	// Undecided is the verdict if the rulebase does not decide whether the document
+	// requires consent, e.g. because of cycles or unresolved issues, the statement is undecided
+	Undecided Verdict = "UNDECIDED"
+)
+
+// verdictStatement is the statement of the argument graph whose label is the verdict
+const verdictStatement = "notDocConsentRequired"
+
+// ComplianceResult is the result of checking a document against a rulebase.
+// The explanation is returned whatever the verdict is. If the verdict is undecided,
+// Issues lists the unresolved issues the verdict depends on.
+type ComplianceResult struct {
+	Verdict     Verdict
+	Explanation Explanation
+	Issues      []UnresolvedIssue
+}
+
+// UnresolvedIssue is an issue of the argument graph none of whose positions is in,
+// with at least one undecided position
+type UnresolvedIssue struct {
+	ID        string          `json:"id"`
+	Positions []IssuePosition `json:"positions"`
+}
+
+// IssuePosition is a position of an unresolved issue with its label,
+// in, out or undecided
+type IssuePosition struct {
+	Statement string `json:"statement"`
+	Label     string `json:"label"`
+}
+
+// verdict returns the verdict of the labelled argument graph and, if it is
+// undecided, the unresolved issues causing it
+func verdict(ag *caes.ArgGraph) (Verdict, []UnresolvedIssue, error) {
+	s, ok := ag.Statements[verdictStatement]
+	if !ok {
+		return NonCompliant, nil, errors.New("notDocConsentRequired is not a statement in the argument graph")
+	}
+	switch s.Label {
+	case caes.In:
+		return Compliant, nil, nil
+	case caes.Out:
+		return NonCompliant, nil, nil
+	default:
+		return Undecided, unresolvedIssues(s), nil
+	}
+}
+
+// unresolvedIssues returns the unresolved issues the label of the undecided statement
+// depends on, following the undecided positions of issues and the undecided premises,
+// exceptions and undercutters of arguments. The issues are ordered by id.
+func unresolvedIssues(stmt *caes.Statement) []UnresolvedIssue {
+	issues := make(map[*caes.Issue]bool)
+	collectUnresolvedIssues(stmt, issues, make(map[*caes.Statement]bool))
+
+	l := make([]UnresolvedIssue, 0, len(issues))
+	for issue := range issues {
+		ui := UnresolvedIssue{ID: issue.Id, Positions: make([]IssuePosition, 0, len(issue.Positions))}
+		for _, pos := range issue.Positions {
+			ui.Positions = append(ui.Positions, IssuePosition{Statement: decodeStatement(pos.Id), Label: pos.Label.String()})
+		}
+		l = append(l, ui)
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].ID < l[j].ID })
+	return l
+}
+
+func collectUnresolvedIssues(stmt *caes.Statement, issues map[*caes.Issue]bool, visited map[*caes.Statement]bool) {
+	if stmt == nil || visited[stmt] || stmt.Label != caes.Undecided {
+		return
+	}
+	visited[stmt] = true
+	if stmt.Issue != nil {
+		issues[stmt.Issue] = true
+		for _, pos := range stmt.Issue.Positions {
+			collectUnresolvedIssues(pos, issues, visited)
+		}
+	}
+	for _, arg := range stmt.Args {
+		for _, p := range arg.Premises {
+			collectUnresolvedIssues(p.Stmt, issues, visited)
+		}
+		collectUnresolvedIssues(arg.Undercutter, issues, visited)
+	}
+}
@@ -0,0 +1,74 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package carneades
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testUndecidedRuleBase concludes that consent is required if it is not required,
+// so that the docConsent issue can not be resolved
+var testUndecidedRuleBase = strings.Replace(testRuleBase, "argument_schemes:\n", `argument_schemes:
+  - id: paradox
+    weight:
+       constant: 0.5
+    premises:
+      - notDocConsentRequired
+    conclusions:
+      - docConsentRequired
+`, 1)
+
+func TestComplianceChecker_Check(t *testing.T) {
+	checker := MakeComplianceChecker()
+	undecided, err := checker.GetTheory("undecided", "undecided", strings.NewReader(testUndecidedRuleBase))
+	if err != nil {
+		t.Fatalf("GetTheory() error = %v", err)
+	}
+	issues := []UnresolvedIssue{{Positions: []IssuePosition{
+		{Statement: "docConsentRequired", Label: "undecided"},
+		{Statement: "notDocConsentRequired", Label: "undecided"},
+	}}}
+
+	tests := []struct {
+		name       string
+		undecided  bool
+		actions    []string
+		want       Verdict
+		wantIssues []UnresolvedIssue
+	}{
+		{"compliant", false, []string{"provide"}, Compliant, nil},
+		{"non compliant", false, []string{"provide", "market"}, NonCompliant, nil},
+		{"undecided", true, []string{"provide"}, Undecided, issues},
+	}
+	for _, tt := range tests {
+		theory := testTheory(t)
+		if tt.undecided {
+			theory = undecided
+		}
+		got, err := checker.Check(theory, actionDoc(tt.actions...))
+		if err != nil {
+			t.Errorf("%q. ComplianceChecker.Check() error = %v", tt.name, err)
+			continue
+		}
+		if got.Verdict != tt.want {
+			t.Errorf("%q. ComplianceChecker.Check() verdict = %v, want %v", tt.name, got.Verdict, tt.want)
+		}
+		// the ids of the issues are generated
+		for i := range got.Issues {
+			got.Issues[i].ID = ""
+		}
+		if !reflect.DeepEqual(got.Issues, tt.wantIssues) {
+			t.Errorf("%q. ComplianceChecker.Check() issues = %+v, want %+v", tt.name, got.Issues, tt.wantIssues)
+		}
+		if got.Explanation == nil {
+			t.Errorf("%q. ComplianceChecker.Check() explanation = nil", tt.name)
+		}
+		// an undecided document is not compliant
+		if ok, _, _ := checker.IsCompliant(theory, actionDoc(tt.actions...)); ok != (tt.want == Compliant) {
+			t.Errorf("%q. ComplianceChecker.IsCompliant() = %v", tt.name, ok)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"runtime"
 	"strconv"
 
 	"path/filepath"
@@ -25,21 +26,28 @@ import (
 var cfgWebDir string
 var cfgJwtKey string
 var cfgRulebaseDir string
+var cfgTaxonomyDir string
+var cfgRulebaseWatch int
 
 func init() {
 	flag.StringVar(&cfgWebDir, "webdir", "", "The root directory for serving web content")
 	flag.StringVar(&cfgJwtKey, "jwtkey", "", "The secret used to sign the JWT")
 	flag.StringVar(&cfgRulebaseDir, "rulebasedir", "", "The Directory to the Rulebases")
+	flag.StringVar(&cfgTaxonomyDir, "taxonomydir", "", "The Directory to the taxonomies of the locales")
+	flag.IntVar(&cfgRulebaseWatch, "rulebasewatch", 0, "The interval in seconds for reloading changed Rulebases, 0 to disable reloading")
 	flag.Parse()
 }
 
 //Configuration contains configuration values like the JWT Key or the RulebaseDir.
 // these values can be set via configuration.json file, environment variables or flags
 type Configuration struct {
-	DBConfig    *structs.DBConf `json:"database,omitempty"`
-	JwtKey      []byte          `json:"jwtkey,omitempty"`
-	WebDir      string          `json:"webdir,omitempty"`
-	RulebaseDir string          `json:"rulebasedir,omitempty"`
+	DBConfig      *structs.DBConf     `json:"database,omitempty"`
+	JwtKey        []byte              `json:"jwtkey,omitempty"`
+	WebDir        string              `json:"webdir,omitempty"`
+	RulebaseDir   string              `json:"rulebasedir,omitempty"`
+	TaxonomyDir   string              `json:"taxonomydir,omitempty"`   // directory of the files taxonomy-<locale>.json
+	RulebaseWatch int                 `json:"rulebasewatch,omitempty"` // interval in seconds for reloading changed rulebases, 0 to disable
+	Search        *structs.SearchConf `json:"search,omitempty"`
 }
 
 //NewConfiguration is the Constructor for a new structs.Configuration struct.
@@ -58,6 +66,8 @@ func NewConfiguration(confpath string) Configuration {
 	c.JwtKey = key
 	c.WebDir = "src/github.com/Microsoft/DUCK/frontend/dist"
 	c.RulebaseDir = "src/github.com/Microsoft/DUCK/RuleBases"
+	c.TaxonomyDir = "src/github.com/Microsoft/DUCK/frontend/src/assets/config"
+	c.Search = &structs.SearchConf{Workers: runtime.NumCPU(), MaxEvaluations: 1000, Timeout: 30}
 
 	//overwrite defaults with information from config file
 	if err := c.getFileConfig(confpath); err != nil {
@@ -73,7 +83,7 @@ func NewConfiguration(confpath string) Configuration {
 	return c
 }
 
-//If there is an env var GOPATH and RulebaseDir or WebDir are relative paths they are assumed to be ralative to the gopath
+//If there is an env var GOPATH and RulebaseDir, WebDir or TaxonomyDir are relative paths they are assumed to be ralative to the gopath
 func (c *Configuration) setAbsPaths() {
 	goPath := os.Getenv("GOPATH")
 	if goPath != "" {
@@ -85,6 +95,10 @@ func (c *Configuration) setAbsPaths() {
 		if !filepath.IsAbs(c.WebDir) {
 			c.WebDir = filepath.Join(goPath, c.WebDir)
 		}
+
+		if !filepath.IsAbs(c.TaxonomyDir) {
+			c.TaxonomyDir = filepath.Join(goPath, c.TaxonomyDir)
+		}
 	}
 }
 
@@ -100,6 +114,12 @@ func (c *Configuration) getFlags() {
 	if cfgRulebaseDir != "" {
 		c.RulebaseDir = cfgRulebaseDir
 	}
+	if cfgTaxonomyDir != "" {
+		c.TaxonomyDir = cfgTaxonomyDir
+	}
+	if cfgRulebaseWatch != 0 {
+		c.RulebaseWatch = cfgRulebaseWatch
+	}
 
 }
 
@@ -133,6 +153,18 @@ func (c *Configuration) getEnv() {
 	if env != "" {
 		c.RulebaseDir = env
 	}
+	env = os.Getenv("DUCK_TAXONOMYDIR")
+	if env != "" {
+		c.TaxonomyDir = env
+	}
+	env = os.Getenv("DUCK_RULEBASEWATCH")
+	if env != "" {
+		if w, err := strconv.Atoi(env); err == nil {
+			c.RulebaseWatch = w
+		} else {
+			log.Printf("Could not read value for RULEBASEWATCH: %s", err)
+		}
+	}
 	//has to be not empty and also something like a boolean to be set
 	env = os.Getenv("DUCK_DATABASE.LOCATION")
 	if env != "" {
@@ -158,6 +190,30 @@ func (c *Configuration) getEnv() {
 	if env != "" {
 		c.DBConfig.Name = env
 	}
+	env = os.Getenv("DUCK_SEARCH.WORKERS")
+	if env != "" {
+		if w, err := strconv.Atoi(env); err == nil {
+			c.Search.Workers = w
+		} else {
+			log.Printf("Could not read value for WORKERS: %s", err)
+		}
+	}
+	env = os.Getenv("DUCK_SEARCH.MAXEVALUATIONS")
+	if env != "" {
+		if m, err := strconv.Atoi(env); err == nil {
+			c.Search.MaxEvaluations = m
+		} else {
+			log.Printf("Could not read value for MAXEVALUATIONS: %s", err)
+		}
+	}
+	env = os.Getenv("DUCK_SEARCH.TIMEOUT")
+	if env != "" {
+		if t, err := strconv.Atoi(env); err == nil {
+			c.Search.Timeout = t
+		} else {
+			log.Printf("Could not read value for TIMEOUT: %s", err)
+		}
+	}
 }
 
 func randomJWT() ([]byte, error) {
@@ -4,6 +4,8 @@
 package db
 
 import (
+	"time"
+
 	"github.com/Microsoft/DUCK/backend/ducklib/structs"
 	"github.com/Microsoft/DUCK/backend/pluginregistry"
 	"github.com/twinj/uuid"
@@ -126,6 +128,13 @@ func (database *Database) GetDocumentSummariesForUser(userid string) ([]structs.
 
 }
 
+//GetAllDocuments returns all documents of all users.
+func (database *Database) GetAllDocuments() ([]structs.Document, error) {
+
+	return database.db.GetAllDocuments()
+
+}
+
 //DeleteDocument deletes the Document with the specified id.
 func (database *Database) DeleteDocument(id string) error {
 
@@ -165,3 +174,93 @@ func (database *Database) PostDocument(doc structs.Document) (ID string, err err
 	return uuid, database.db.NewDocument(doc)
 
 }
+
+/*
+AssumptionSet DB operations
+
+*/
+
+//GetAssumptionSets returns all assumption sets.
+func (database *Database) GetAssumptionSets() ([]structs.AssumptionSet, error) {
+
+	return database.db.GetAssumptionSets()
+
+}
+
+//GetAssumptionSet returns the assumption set with the specified id.
+func (database *Database) GetAssumptionSet(id string) (structs.AssumptionSet, error) {
+
+	return database.db.GetAssumptionSet(id)
+
+}
+
+//DeleteAssumptionSet deletes the assumption set with the specified id.
+func (database *Database) DeleteAssumptionSet(id string) error {
+
+	return database.db.DeleteAssumptionSet(id)
+
+}
+
+//PutAssumptionSet replaces the assumption set in the database with the same ID.
+func (database *Database) PutAssumptionSet(set structs.AssumptionSet) error {
+	if set.Name == "" {
+		return structs.NewHTTPError("No Assumption Set Name submitted", 400)
+	}
+
+	return database.db.UpdateAssumptionSet(set)
+
+}
+
+//PostAssumptionSet creates a new assumption set in the database.
+func (database *Database) PostAssumptionSet(set structs.AssumptionSet) (ID string, err error) {
+	if set.Name == "" {
+		return "", structs.NewHTTPError("No Assumption Set Name submitted", 400)
+	}
+
+	if set.Owner == "" {
+		return "", structs.NewHTTPError("No Assumption Set Owner submitted", 400)
+	}
+
+	u := uuid.NewV4()
+	uuid := uuid.Formatter(u, uuid.Clean)
+	set.ID = uuid
+
+	return uuid, database.db.NewAssumptionSet(set)
+
+}
+
+/*
+Compliance DB operations
+
+*/
+
+//PostComplianceRecord stores the result of a compliance check in the database.
+//The timestamp of the record is set to the current time if it is not set.
+func (database *Database) PostComplianceRecord(record structs.ComplianceRecord) (ID string, err error) {
+	if record.DocumentID == "" {
+		return "", structs.NewHTTPError("No Document ID submitted", 400)
+	}
+
+	if record.RuleBaseID == "" {
+		return "", structs.NewHTTPError("No Rulebase ID submitted", 400)
+	}
+
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now().UTC()
+	}
+
+	u := uuid.NewV4()
+	uuid := uuid.Formatter(u, uuid.Clean)
+	record.ID = uuid
+
+	return uuid, database.db.NewComplianceRecord(record)
+
+}
+
+//GetComplianceHistory returns the compliance records of the document with the specified id,
+//oldest first. If ruleBaseID is not empty, only the records of this rulebase are returned.
+func (database *Database) GetComplianceHistory(documentID string, ruleBaseID string) ([]structs.ComplianceRecord, error) {
+
+	return database.db.GetComplianceHistory(documentID, ruleBaseID)
+
+}
@@ -7,7 +7,9 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"reflect"
+	"sort"
 	"testing"
+	"time"
 
 	"fmt"
 
@@ -57,6 +59,10 @@ func TestDatabase(t *testing.T) {
 
 	t.Run("UserDicts", testDatabase_DICTS)
 
+	t.Run("ComplianceHistory", testDatabase_ComplianceHistory)
+
+	t.Run("AssumptionSets", testDatabase_AssumptionSets)
+
 	if err := loadDocs(); err != nil {
 		t.Error(err.Error())
 		t.Skip("No testfixtures no Documenttests")
@@ -65,6 +71,7 @@ func TestDatabase(t *testing.T) {
 	t.Run("PostDocument", testDatabase_PostDocument)
 	t.Run("GetDocument", testDatabase_GetDocument)
 	t.Run("GetDocumentSummariesForUser", testDatabase_GetDocumentSummariesForUser)
+	t.Run("GetAllDocuments", testDatabase_GetAllDocuments)
 	t.Run("PutDocument", testDatabase_PutDocument)
 	t.Run("DeleteDocument", testDatabase_DeleteDocument)
 
@@ -430,6 +437,28 @@ func testDatabase_GetDocumentSummariesForUser(t *testing.T) {
 	}
 }
 
+func testDatabase_GetAllDocuments(t *testing.T) {
+	want := []string{}
+	for _, d := range documents {
+		if d.Pass {
+			want = append(want, d.Document.ID)
+		}
+	}
+	sort.Strings(want)
+
+	got, err := testDB.GetAllDocuments()
+	if err != nil {
+		t.Fatalf("Database.GetAllDocuments() error = %v", err)
+	}
+	ids := []string{}
+	for _, d := range got {
+		ids = append(ids, d.ID)
+	}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("Database.GetAllDocuments() = %v, want %v", ids, want)
+	}
+}
+
 func testDatabase_DeleteDocument(t *testing.T) {
 
 	tests := []struct {
@@ -511,3 +540,108 @@ func testDatabase_PostDocument(t *testing.T) {
 		}
 	}
 }
+
+func testDatabase_ComplianceHistory(t *testing.T) {
+	day := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	records := []struct {
+		name    string
+		record  structs.ComplianceRecord
+		wantErr bool
+	}{
+		{"rulebase a, second", structs.ComplianceRecord{DocumentID: "doc1", DocumentRevision: "2", RuleBaseID: "a", Compliant: "COMPLIANT", Timestamp: day.Add(time.Hour)}, false},
+		{"rulebase a, first", structs.ComplianceRecord{DocumentID: "doc1", DocumentRevision: "1", RuleBaseID: "a", Compliant: "NON_COMPLIANT", Timestamp: day}, false},
+		{"rulebase b", structs.ComplianceRecord{DocumentID: "doc1", DocumentRevision: "1", RuleBaseID: "b", Compliant: "COMPLIANT", Timestamp: day.Add(time.Minute)}, false},
+		{"other document", structs.ComplianceRecord{DocumentID: "doc2", RuleBaseID: "a", Compliant: "COMPLIANT"}, false},
+		{"no document", structs.ComplianceRecord{RuleBaseID: "a", Compliant: "COMPLIANT"}, true},
+		{"no rulebase", structs.ComplianceRecord{DocumentID: "doc1", Compliant: "COMPLIANT"}, true},
+	}
+	for _, tt := range records {
+		gotID, err := testDB.PostComplianceRecord(tt.record)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q. Database.PostComplianceRecord() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && gotID == "" {
+			t.Errorf("%q. Database.PostComplianceRecord() returned no ID", tt.name)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		documentID string
+		ruleBaseID string
+		want       []string // revision and rulebase of the records, in order
+	}{
+		{"all rulebases", "doc1", "", []string{"1a", "1b", "2a"}},
+		{"one rulebase", "doc1", "a", []string{"1a", "2a"}},
+		{"unknown rulebase", "doc1", "c", []string{}},
+		{"unknown document", "doc3", "", []string{}},
+	}
+	for _, tt := range tests {
+		history, err := testDB.GetComplianceHistory(tt.documentID, tt.ruleBaseID)
+		if err != nil {
+			t.Errorf("%q. Database.GetComplianceHistory() error = %v", tt.name, err)
+			continue
+		}
+		got := []string{}
+		for _, r := range history {
+			got = append(got, r.DocumentRevision+r.RuleBaseID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q. Database.GetComplianceHistory() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func testDatabase_AssumptionSets(t *testing.T) {
+	posts := []struct {
+		name    string
+		set     structs.AssumptionSet
+		wantErr bool
+	}{
+		{"valid", structs.AssumptionSet{Name: "EU", Owner: "user1", Facts: []string{"euResidents"}}, false},
+		{"no name", structs.AssumptionSet{Owner: "user1"}, true},
+		{"no owner", structs.AssumptionSet{Name: "EU"}, true},
+	}
+	id := ""
+	for _, tt := range posts {
+		gotID, err := testDB.PostAssumptionSet(tt.set)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q. Database.PostAssumptionSet() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil {
+			id = gotID
+		}
+	}
+	if id == "" {
+		t.Fatal("Database.PostAssumptionSet() returned no ID")
+	}
+
+	set, err := testDB.GetAssumptionSet(id)
+	if err != nil {
+		t.Fatalf("Database.GetAssumptionSet() error = %v", err)
+	}
+	if set.Name != "EU" || !reflect.DeepEqual(set.Facts, []string{"euResidents"}) {
+		t.Errorf("Database.GetAssumptionSet() = %+v", set)
+	}
+	set.Facts = append(set.Facts, "isInEu(custom_region)")
+	if err := testDB.PutAssumptionSet(set); err != nil {
+		t.Errorf("Database.PutAssumptionSet() error = %v", err)
+	}
+	if got, _ := testDB.GetAssumptionSet(id); len(got.Facts) != 2 {
+		t.Errorf("Database.PutAssumptionSet() facts = %v, want 2 facts", got.Facts)
+	}
+	sets, err := testDB.GetAssumptionSets()
+	if err != nil || len(sets) != 1 {
+		t.Errorf("Database.GetAssumptionSets() = %v, %v, want 1 set", sets, err)
+	}
+
+	if err := testDB.DeleteAssumptionSet(id); err != nil {
+		t.Errorf("Database.DeleteAssumptionSet() error = %v", err)
+	}
+	if _, err := testDB.GetAssumptionSet(id); err == nil {
+		t.Error("Database.GetAssumptionSet() after delete error = nil, want an error")
+	}
+}
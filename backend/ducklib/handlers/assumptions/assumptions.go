@@ -0,0 +1,188 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package assumptions
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/carneades"
+	"github.com/Microsoft/DUCK/backend/ducklib/db"
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+	"github.com/labstack/echo"
+)
+
+//Handler ...
+type Handler struct {
+	Db *db.Database
+}
+
+//GetAssumptionSets returns all assumption sets. Assumption sets are shared by all users,
+//so that documents of different users can reference the same set.
+func (h *Handler) GetAssumptionSets(c echo.Context) error {
+	sets, err := h.Db.GetAssumptionSets()
+	if err != nil {
+		log.Printf("Error in getAssumptionSetsHandler: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	return c.JSON(http.StatusOK, sets)
+}
+
+//GetAssumptionSet returns an assumption set if it exists in the database
+//
+//Context-Parameter:
+//	setid		the id of the assumption set
+func (h *Handler) GetAssumptionSet(c echo.Context) error {
+	set, err := h.Db.GetAssumptionSet(c.Param("setid"))
+	if err != nil {
+		log.Printf("Error in getAssumptionSetHandler: %s", err)
+		e := err.Error()
+
+		return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+	}
+	return c.JSON(http.StatusOK, set)
+}
+
+//PostAssumptionSet creates a new assumption set owned by the user in the database.
+//The facts of the set must be ground atomic formulas, e.g. isInEu(custom_region).
+//
+//Context-Parameter
+//	in RequestBody:		the new assumption set
+//
+//Returns the new assumption set if successful
+func (h *Handler) PostAssumptionSet(c echo.Context) error {
+	set := new(structs.AssumptionSet)
+	if err := c.Bind(set); err != nil {
+		log.Printf("Error in postAssumptionSetHandler while trying to bind new assumption set to struct: %s", err)
+
+		e := err.Error()
+		return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+	}
+	owner, err := structs.UserID(c)
+	if err != nil {
+		log.Printf("Error in postAssumptionSetHandler: %s", err)
+
+		e := err.Error()
+		return c.JSON(http.StatusUnauthorized, structs.Response{Ok: false, Reason: &e})
+	}
+	set.Owner = owner
+	set.Revision = ""
+	if err := carneades.NormalizeAssumptionSet(set); err != nil {
+		log.Printf("Error in postAssumptionSetHandler while validating the facts: %s", err)
+
+		e := err.Error()
+		return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+	}
+
+	id, err := h.Db.PostAssumptionSet(*set)
+	if err != nil {
+		log.Printf("Error in postAssumptionSetHandler while trying to create assumption set in database: %s", err)
+
+		e := err.Error()
+		return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+	}
+	newSet, err := h.Db.GetAssumptionSet(id)
+	if err != nil {
+		log.Printf("Error in postAssumptionSetHandler while trying to get new assumption set: %s", err)
+
+		e := err.Error()
+		return c.JSON(http.StatusInternalServerError, structs.Response{Ok: false, Reason: &e})
+	}
+
+	return c.JSON(http.StatusCreated, newSet)
+}
+
+//PutAssumptionSet replaces an assumption set of the user in the database with a newer version
+//if both have the same revision number
+//
+//Context-Parameter
+//	setid				the id of the assumption set
+//	in RequestBody		the new version of the assumption set
+//
+//Returns the new version if successful
+func (h *Handler) PutAssumptionSet(c echo.Context) error {
+	set := new(structs.AssumptionSet)
+	if err := c.Bind(set); err != nil {
+		log.Printf("Error in putAssumptionSetHandler while trying to bind assumption set to struct: %s", err)
+
+		e := err.Error()
+		return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+	}
+	old, err := h.Db.GetAssumptionSet(c.Param("setid"))
+	if err != nil {
+		log.Printf("Error in putAssumptionSetHandler trying to get assumption set from database: %s", err)
+
+		e := err.Error()
+		return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+	}
+	if err := old.IsUserOwner(c); err != nil {
+		log.Printf("Error in putAssumptionSetHandler: %s", err)
+
+		e := err.Error()
+		return c.JSON(http.StatusForbidden, structs.Response{Ok: false, Reason: &e})
+	}
+	set.ID = old.ID
+	set.Owner = old.Owner
+	if err := carneades.NormalizeAssumptionSet(set); err != nil {
+		log.Printf("Error in putAssumptionSetHandler while validating the facts: %s", err)
+
+		e := err.Error()
+		return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+	}
+
+	if err := h.Db.PutAssumptionSet(*set); err != nil {
+		log.Printf("Error in putAssumptionSetHandler while trying to update assumption set in database: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	newSet, err := h.Db.GetAssumptionSet(set.ID)
+	if err != nil {
+		log.Printf("Error in putAssumptionSetHandler while trying to get updated assumption set: %s", err)
+
+		e := err.Error()
+		return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+	}
+	return c.JSON(http.StatusOK, newSet)
+}
+
+//DeleteAssumptionSet deletes an assumption set of the user if it exists in the database.
+//Documents referencing the deleted set are checked without assumptions.
+//
+//Context-Parameter:
+//	setid		the id of the assumption set
+func (h *Handler) DeleteAssumptionSet(c echo.Context) error {
+	set, err := h.Db.GetAssumptionSet(c.Param("setid"))
+	if err != nil {
+		log.Printf("Error in deleteAssumptionSetHandler trying to get assumption set from database: %s", err)
+
+		e := err.Error()
+		return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+	}
+	if err := set.IsUserOwner(c); err != nil {
+		log.Printf("Error in deleteAssumptionSetHandler: %s", err)
+
+		e := err.Error()
+		return c.JSON(http.StatusForbidden, structs.Response{Ok: false, Reason: &e})
+	}
+
+	if err := h.Db.DeleteAssumptionSet(set.ID); err != nil {
+		log.Printf("Error in deleteAssumptionSetHandler: %s", err)
+
+		e := err.Error()
+		return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+	}
+
+	return c.JSON(http.StatusOK, structs.Response{Ok: true})
+}
@@ -7,15 +7,18 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/Microsoft/DUCK/backend/ducklib/carneades"
 	"github.com/Microsoft/DUCK/backend/ducklib/db"
 	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+	"github.com/Microsoft/DUCK/backend/ducklib/taxonomy"
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/labstack/echo"
 )
 
 //Handler ...
 type Handler struct {
-	Db *db.Database
+	Db         *db.Database
+	Taxonomies *taxonomy.Service
 }
 
 //GetDocSummaries returns ID and name for each Document that has the field owner with a specified userID
@@ -79,6 +82,40 @@ func (h *Handler) GetDoc(c echo.Context) error {
 	return c.JSON(http.StatusOK, doc)
 }
 
+//GetComplianceHistory returns the results of the compliance checks of a document, oldest first
+//
+//Context-Parameter:
+//	docid		a docid string which is pointing to the document
+//	rulebase	query parameter, the id of a rulebase to return only its results (optional)
+func (h *Handler) GetComplianceHistory(c echo.Context) error {
+	doc, err := h.Db.GetDocument(c.Param("docid"))
+	if err != nil {
+		log.Printf("Error in getComplianceHistoryHandler: %s", err)
+		e := err.Error()
+
+		return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+	}
+	err = doc.IsUserOwner(c)
+	if err != nil {
+		log.Printf("Error in getComplianceHistoryHandler: %s", err)
+		e := err.Error()
+
+		return c.JSON(http.StatusForbidden, structs.Response{Ok: false, Reason: &e})
+	}
+	history, err := h.Db.GetComplianceHistory(doc.ID, c.QueryParam("rulebase"))
+	if err != nil {
+		log.Printf("Error in getComplianceHistoryHandler: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	return c.JSON(http.StatusOK, history)
+}
+
 //CopyStatements  copies the Statements from one document
 //in the database to a new one
 //
@@ -236,3 +273,43 @@ func (h *Handler) PostDoc(c echo.Context) error {
 	return c.JSON(http.StatusCreated, docu)
 
 }
+
+//Validate normalizes a document without checking it against a rulebase or saving it.
+//If statements of the document cannot be normalized, all their problems are
+//reported with status 422 Unprocessable Entity.
+//
+//Context-Parameter
+//	in RequestBody:		the Document
+func (h *Handler) Validate(c echo.Context) error {
+	doc := new(structs.Document)
+	if err := c.Bind(doc); err != nil {
+		log.Printf("Error in validateHandler while trying to bind document to struct: %s", err)
+
+		e := err.Error()
+		return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+	}
+	normalizer, err := carneades.NewNormalizer(*doc, h.Db, h.Taxonomies)
+	if err != nil {
+		log.Printf("Error in validateHandler while trying to normalize document: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	if _, err := normalizer.GetNormalized(); err != nil {
+		log.Printf("Error in validateHandler while normalizing: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.ValidationErrors:
+			return c.JSON(http.StatusUnprocessableEntity, structs.ValidationResponse{Ok: false, Reason: &e, Errors: t})
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	return c.JSON(http.StatusOK, structs.ValidationResponse{Ok: true, Errors: structs.ValidationErrors{}})
+}
@@ -4,20 +4,78 @@
 package rulebases
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/Microsoft/DUCK/backend/ducklib/carneades"
 	"github.com/Microsoft/DUCK/backend/ducklib/db"
 	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+	"github.com/Microsoft/DUCK/backend/ducklib/taxonomy"
 	"github.com/labstack/echo"
 )
 
 //Handler ...
 type Handler struct {
-	Db      *db.Database
-	WebDir  string
-	Checker *carneades.ComplianceCheckerPlugin
+	Db         *db.Database
+	Taxonomies *taxonomy.Service
+	Checker    *carneades.ComplianceCheckerPlugin
+}
+
+//Impact checks every stored document against the rulebase and against a candidate version
+//of the rulebase, which is not saved, and reports the documents whose verdict or
+//explanation would change if the candidate replaced the rulebase
+//
+//Context-Parameter
+//	baseid			the id of the rulebase
+// 	in RequestBody	the YAML source of the candidate version of the rulebase
+func (h *Handler) Impact(c echo.Context) error {
+	src, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		log.Printf("Error in impactHandler while reading the request body: %s", err)
+		e := err.Error()
+		return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+	}
+	// the lint result tells the modeler why the candidate cannot be analysed,
+	// e.g. because its meta id is not baseid
+	if result := h.Checker.LintRuleBase(src, c.Param("baseid")); !result.Valid {
+		return c.JSON(http.StatusBadRequest, result)
+	}
+	docs, err := h.Db.GetAllDocuments()
+	if err != nil {
+		log.Printf("Error in impactHandler while trying to get the documents from database: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	normalize := func(doc structs.Document) (*carneades.NormalizedDocument, error) {
+		normalizer, err := carneades.NewNormalizer(doc, h.Db, h.Taxonomies)
+		if err != nil {
+			return nil, err
+		}
+		return normalizer.GetNormalized()
+	}
+	report, err := h.Checker.ImpactAnalysis(src, docs, normalize)
+	if err != nil {
+		log.Printf("Error in impactHandler while analysing the candidate rulebase: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	return c.JSON(http.StatusOK, report)
 }
 
 //CheckDoc checks the document against a rulebase for compliance
@@ -47,7 +105,7 @@ func (h *Handler) CheckDoc(c echo.Context) error {
 			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
 		}
 	}
-	normalizer, err := carneades.NewNormalizer(*doc, h.Db, h.WebDir)
+	normalizer, err := carneades.NewNormalizer(*doc, h.Db, h.Taxonomies)
 	if err != nil {
 		log.Printf("Error in checkDocIDHandler while trying to normalize document : %s", err)
 		e := err.Error()
@@ -63,13 +121,15 @@ func (h *Handler) CheckDoc(c echo.Context) error {
 		log.Printf("Error in checkDocHandler while normalizing: %s", err)
 		e := err.Error()
 		switch t := err.(type) {
+		case structs.ValidationErrors:
+			return c.JSON(http.StatusUnprocessableEntity, structs.ValidationResponse{Ok: false, Reason: &e, Errors: t})
 		case structs.HTTPError:
 			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
 		default:
 			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
 		}
 	}
-	ok, exp, err := h.Checker.IsCompliant(id, normDoc)
+	result, err := h.Checker.Check(id, normDoc)
 
 	if err != nil {
 		log.Printf("Error in checkDocHandler while checking for compliance: %s", err)
@@ -81,13 +141,10 @@ func (h *Handler) CheckDoc(c echo.Context) error {
 			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
 		}
 	}
-	flatExp := carneades.FoldExplanation(exp)
+	flatExp := carneades.FoldExplanation(result.Explanation)
 
 	//log.Printf("%#v", flatExp)
-	if ok {
-		return c.JSON(http.StatusOK, structs.ComplianceResponse{Compliant: "COMPLIANT", Explanation: flatExp})
-	}
-	return c.JSON(http.StatusOK, structs.ComplianceResponse{Compliant: "NON_COMPLIANT", Explanation: flatExp})
+	return c.JSON(http.StatusOK, complianceResponse(result, flatExp))
 }
 
 //CheckDocID checks a document from the database against a rulebase for compliance
@@ -111,7 +168,7 @@ func (h *Handler) CheckDocID(c echo.Context) error {
 			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
 		}
 	}
-	normalizer, err := carneades.NewNormalizer(doc, h.Db, h.WebDir)
+	normalizer, err := carneades.NewNormalizer(doc, h.Db, h.Taxonomies)
 	if err != nil {
 		log.Printf("Error in checkDocIDHandler while trying to normalize document : %s", err)
 		e := err.Error()
@@ -127,13 +184,15 @@ func (h *Handler) CheckDocID(c echo.Context) error {
 		log.Printf("Error in checkDocIDHandler while normalizing: %s", err)
 		e := err.Error()
 		switch t := err.(type) {
+		case structs.ValidationErrors:
+			return c.JSON(http.StatusUnprocessableEntity, structs.ValidationResponse{Ok: false, Reason: &e, Errors: t})
 		case structs.HTTPError:
 			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
 		default:
 			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
 		}
 	}
-	ok, exp, err := h.Checker.IsCompliant(id, normDoc)
+	result, err := h.Checker.Check(id, normDoc)
 	if err != nil {
 		log.Printf("Error in checkDocIDHandler while checking for compliance: %s", err)
 		e := err.Error()
@@ -144,19 +203,710 @@ func (h *Handler) CheckDocID(c echo.Context) error {
 			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
 		}
 	}
-	if ok {
-		return c.JSON(http.StatusOK, structs.ComplianceResponse{Compliant: "COMPLIANT", Explanation: exp})
+	h.recordCompliance(c, doc, id, result)
+	return c.JSON(http.StatusOK, complianceResponse(result, result.Explanation))
+
+}
+
+//CheckDocAll checks the document against every loaded rulebase, or the rulebases
+//selected with the rulebases query parameter, normalizing the document only once
+//
+//Context-Parameter
+// 	in RequestBody	the document
+//	rulebases		query parameter, comma separated ids of the rulebases (optional)
+func (h *Handler) CheckDocAll(c echo.Context) error {
+	doc := new(structs.Document)
+	if err := c.Bind(doc); err != nil {
+		log.Printf("Error in checkDocAllHandler while trying to bind document to struct: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	return h.checkAll(c, *doc, false)
+}
+
+//CheckDocIDAll checks a document from the database against every loaded rulebase, or the
+//rulebases selected with the rulebases query parameter, normalizing the document only once
+//
+//Context-Parameter
+// 	docid		the id of the document
+//	rulebases	query parameter, comma separated ids of the rulebases (optional)
+func (h *Handler) CheckDocIDAll(c echo.Context) error {
+	doc, err := h.Db.GetDocument(c.Param("docid"))
+	if err != nil {
+		log.Printf("Error in checkDocIDAllHandler while trying to get document from database: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	return h.checkAll(c, doc, true)
+}
+
+//checkAll normalizes the document and returns the verdicts and folded explanations
+//of the rulebases selected with the rulebases query parameter. The results are recorded
+//if the document is stored, i.e. has been read from the database.
+func (h *Handler) checkAll(c echo.Context, doc structs.Document, stored bool) error {
+	normalizer, err := carneades.NewNormalizer(doc, h.Db, h.Taxonomies)
+	if err != nil {
+		log.Printf("Error in checkAllHandler while trying to normalize document : %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	normDoc, err := normalizer.GetNormalized()
+	if err != nil {
+		log.Printf("Error in checkAllHandler while normalizing: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.ValidationErrors:
+			return c.JSON(http.StatusUnprocessableEntity, structs.ValidationResponse{Ok: false, Reason: &e, Errors: t})
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+
+	var ids []string
+	if q := c.QueryParam("rulebases"); q != "" {
+		ids = strings.Split(q, ",")
+	}
+	resp := make(structs.MultiComplianceResponse)
+	for id, r := range h.Checker.IsCompliantAll(ids, normDoc) {
+		switch {
+		case r.Err != nil:
+			e := r.Err.Error()
+			resp[id] = structs.ComplianceResponse{Reason: &e}
+		default:
+			result := carneades.ComplianceResult{Verdict: r.Verdict, Explanation: r.Explanation, Issues: r.Issues}
+			resp[id] = complianceResponse(result, carneades.FoldExplanation(r.Explanation))
+			if stored {
+				h.recordCompliance(c, doc, id, result)
+			}
+		}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+//complianceResponse returns the response for the result of a compliance check with the
+//explanation in the form requested, listing the unresolved issues of an undecided verdict
+func complianceResponse(result carneades.ComplianceResult, explanation interface{}) structs.ComplianceResponse {
+	resp := structs.ComplianceResponse{Compliant: string(result.Verdict), Explanation: explanation}
+	if len(result.Issues) > 0 {
+		resp.Issues = result.Issues
+	}
+	return resp
+}
+
+//recordCompliance persists the result of checking a document read from the database
+//against a rulebase in the compliance history of the document, with the folded explanation
+//whatever form the response has. Posted documents are not recorded, as their content may
+//differ from the stored revision. Errors are logged, as the check itself succeeded.
+func (h *Handler) recordCompliance(c echo.Context, doc structs.Document, ruleBaseID string, result carneades.ComplianceResult) {
+	if doc.ID == "" || h.Db == nil {
+		return
+	}
+	user, err := structs.UserID(c)
+	if err != nil {
+		log.Printf("Error while recording compliance of document %s: %s", doc.ID, err)
+	}
+	desc := h.Checker.RuleBaseDescriptions()[ruleBaseID]
+	record := structs.ComplianceRecord{
+		DocumentID:       doc.ID,
+		DocumentRevision: doc.Revision,
+		RuleBaseID:       ruleBaseID,
+		RuleBaseVersion:  desc.Version,
+		RuleBaseRevision: desc.Revision,
+		Compliant:        string(result.Verdict),
+		Explanation:      carneades.FoldExplanation(result.Explanation),
+		User:             user,
+	}
+	if _, err := h.Db.PostComplianceRecord(record); err != nil {
+		log.Printf("Error while recording compliance of document %s: %s", doc.ID, err)
+	}
+}
+
+//GetGraph returns the labelled argument graph constructed while checking
+//a document from the database against a rulebase
+//
+//Context-Parameter
+//	baseid		the id of the rulebase
+// 	documentid	the id of the document
+//	format		query parameter, one of graphml (default), dot or yaml
+func (h *Handler) GetGraph(c echo.Context) error {
+	id := c.Param("baseid")
+	docid := c.Param("documentid")
+
+	format := c.QueryParam("format")
+	if format == "" {
+		format = carneades.GraphML
+	}
+	contentType, ok := carneades.GraphContentTypes[format]
+	if !ok {
+		e := fmt.Sprintf("Unsupported graph format: %s", format)
+		return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+	}
+
+	doc, err := h.Db.GetDocument(docid)
+	if err != nil {
+		log.Printf("Error in getGraphHandler while trying to get document from database: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	normalizer, err := carneades.NewNormalizer(doc, h.Db, h.Taxonomies)
+	if err != nil {
+		log.Printf("Error in getGraphHandler while trying to normalize document : %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	normDoc, err := normalizer.GetNormalized()
+	if err != nil {
+		log.Printf("Error in getGraphHandler while normalizing: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.ValidationErrors:
+			return c.JSON(http.StatusUnprocessableEntity, structs.ValidationResponse{Ok: false, Reason: &e, Errors: t})
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	ag, err := h.Checker.ArgumentGraph(id, normDoc)
+	if err != nil {
+		log.Printf("Error in getGraphHandler while constructing the argument graph: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := carneades.ExportGraph(&buf, ag, format); err != nil {
+		log.Printf("Error in getGraphHandler while exporting the argument graph: %s", err)
+		e := err.Error()
+		return c.JSON(http.StatusInternalServerError, structs.Response{Ok: false, Reason: &e})
+	}
+	return c.Blob(http.StatusOK, contentType, buf.Bytes())
+}
+
+//Query matches a term pattern against the statements of the argument graph constructed while
+//checking a document from the database against a rulebase, returning every matching statement
+//with the bindings of the variables of the pattern, its label and the arguments pro and con
+//
+//Context-Parameter
+//	baseid		the id of the rulebase
+// 	documentid	the id of the document
+//	in RequestBody	the query, e.g. {"query": "compatiblePurpose(S1,S2)"}
+func (h *Handler) Query(c echo.Context) error {
+	id := c.Param("baseid")
+	docid := c.Param("documentid")
+
+	query := new(structs.QueryRequest)
+	if err := c.Bind(query); err != nil {
+		log.Printf("Error in queryHandler while trying to bind query to struct: %s", err)
+		e := err.Error()
+		return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+	}
+	doc, err := h.Db.GetDocument(docid)
+	if err != nil {
+		log.Printf("Error in queryHandler while trying to get document from database: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	normalizer, err := carneades.NewNormalizer(doc, h.Db, h.Taxonomies)
+	if err != nil {
+		log.Printf("Error in queryHandler while trying to normalize document : %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	normDoc, err := normalizer.GetNormalized()
+	if err != nil {
+		log.Printf("Error in queryHandler while normalizing: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.ValidationErrors:
+			return c.JSON(http.StatusUnprocessableEntity, structs.ValidationResponse{Ok: false, Reason: &e, Errors: t})
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	results, err := h.Checker.Query(id, normDoc, query.Query)
+	if err != nil {
+		log.Printf("Error in queryHandler while querying the argument graph: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	return c.JSON(http.StatusOK, results)
+}
+
+//Session checks the edited version of a document against a rulebase in a session kept for the
+//revision of the document, so that only the statements changed since the previous check of the
+//session are added to or retracted from its argument graph. The result includes the changes of
+//the explanation since the previous check. Sessions are removed when they were not used for a while.
+//
+//Context-Parameter
+//	baseid		the id of the rulebase
+// 	documentid	the id of the document
+//	in RequestBody	the edited document, whose revision is the revision it was loaded with
+func (h *Handler) Session(c echo.Context) error {
+	id := c.Param("baseid")
+	docid := c.Param("documentid")
+
+	doc := new(structs.Document)
+	if err := c.Bind(doc); err != nil {
+		log.Printf("Error in sessionHandler while trying to bind document to struct: %s", err)
+		e := err.Error()
+		return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+	}
+	normalizer, err := carneades.NewNormalizer(*doc, h.Db, h.Taxonomies)
+	if err != nil {
+		log.Printf("Error in sessionHandler while trying to normalize document : %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	normDoc, err := normalizer.GetNormalized()
+	if err != nil {
+		log.Printf("Error in sessionHandler while normalizing: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.ValidationErrors:
+			return c.JSON(http.StatusUnprocessableEntity, structs.ValidationResponse{Ok: false, Reason: &e, Errors: t})
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	result, err := h.Checker.CheckInSession(id, docid, doc.Revision, normDoc)
+	if err != nil {
+		log.Printf("Error in sessionHandler while checking for compliance: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
 	}
+	return c.JSON(http.StatusOK, result)
+}
 
-	return c.JSON(http.StatusOK, structs.ComplianceResponse{Compliant: "NON_COMPLIANT", Explanation: exp})
+//Alternatives streams compliant variants of a document from the database, each as a diff
+//against the original document listing the statements which were dropped.
+//The variants are written as newline delimited JSON or, if the client accepts
+//text/event-stream, as Server-Sent Events. The search for variants is cancelled
+//when the client disconnects.
+//
+//Context-Parameter
+//	baseid		the id of the rulebase
+// 	documentid	the id of the document
+//	limit		query parameter, the maximum number of variants to return (optional)
+//	format		query parameter, ndjson (default) or sse
+func (h *Handler) Alternatives(c echo.Context) error {
+	id := c.Param("baseid")
+	docid := c.Param("documentid")
 
+	limit := 0
+	if l := c.QueryParam("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 0 {
+			e := fmt.Sprintf("Invalid limit: %s", l)
+			return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+		}
+		limit = n
+	}
+	sse := c.QueryParam("format") == "sse" ||
+		strings.Contains(c.Request().Header.Get("Accept"), "text/event-stream")
+
+	doc, err := h.Db.GetDocument(docid)
+	if err != nil {
+		log.Printf("Error in alternativesHandler while trying to get document from database: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	normalizer, err := carneades.NewNormalizer(doc, h.Db, h.Taxonomies)
+	if err != nil {
+		log.Printf("Error in alternativesHandler while trying to normalize document : %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	normDoc, err := normalizer.GetNormalized()
+	if err != nil {
+		log.Printf("Error in alternativesHandler while normalizing: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.ValidationErrors:
+			return c.JSON(http.StatusUnprocessableEntity, structs.ValidationResponse{Ok: false, Reason: &e, Errors: t})
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+
+	cncl := carneades.MakeCanceller()
+	compliant, variants, err := h.Checker.CompliantAlternatives(id, normDoc, cncl)
+	if err != nil {
+		log.Printf("Error in alternativesHandler while checking for compliance: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	// a compliant document has no alternatives
+	if compliant {
+		return c.NoContent(http.StatusNoContent)
+	}
+	defer cncl.Cancel()
+
+	res := c.Response()
+	if sse {
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.Header().Set("Cache-Control", "no-cache")
+	} else {
+		res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	}
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	finish := func() error {
+		if sse {
+			fmt.Fprint(res, "event: done\ndata: {}\n\n")
+			res.Flush()
+		}
+		return nil
+	}
+
+	enc := json.NewEncoder(res)
+	disconnected := c.Request().Context().Done()
+	for n := 0; limit == 0 || n < limit; n++ {
+		select {
+		case <-disconnected:
+			return nil
+		case variant, ok := <-variants:
+			if !ok {
+				return finish()
+			}
+			if sse {
+				fmt.Fprint(res, "event: alternative\ndata: ")
+			}
+			alt := carneades.DiffDocuments(normDoc, variant)
+			alt.Document, alt.DroppedClauses = normalizer.Denormalize(variant)
+			// Encode terminates each variant with a newline
+			if err := enc.Encode(alt); err != nil {
+				log.Printf("Error in alternativesHandler while writing variant: %s", err)
+				return nil
+			}
+			if sse {
+				fmt.Fprint(res, "\n")
+			}
+			res.Flush()
+		}
+	}
+	return finish()
+}
+
+//Repair proposes the cheapest set of edits to a document from the database which makes
+//it compliant with a rulebase. Besides removing statements, the edits narrow qualifiers,
+//shrink scopes or replace actions of statements. The repair is null if the document
+//is compliant or no repair was found.
+//
+//Context-Parameter
+//	baseid		the id of the rulebase
+// 	documentid	the id of the document
+func (h *Handler) Repair(c echo.Context) error {
+	id := c.Param("baseid")
+	docid := c.Param("documentid")
+
+	doc, err := h.Db.GetDocument(docid)
+	if err != nil {
+		log.Printf("Error in repairHandler while trying to get document from database: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	normalizer, err := carneades.NewNormalizer(doc, h.Db, h.Taxonomies)
+	if err != nil {
+		log.Printf("Error in repairHandler while trying to normalize document : %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	normDoc, err := normalizer.GetNormalized()
+	if err != nil {
+		log.Printf("Error in repairHandler while normalizing: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.ValidationErrors:
+			return c.JSON(http.StatusUnprocessableEntity, structs.ValidationResponse{Ok: false, Reason: &e, Errors: t})
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	compliant, repair, err := h.Checker.Repair(id, normDoc, normalizer.Taxonomy())
+	if err != nil {
+		log.Printf("Error in repairHandler while searching for a repair: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	if compliant {
+		return c.JSON(http.StatusOK, structs.RepairResponse{Compliant: "COMPLIANT"})
+	}
+	if repair != nil && repair.Document != nil {
+		repair.Denormalized, repair.DroppedClauses = normalizer.Denormalize(repair.Document)
+	}
+	return c.JSON(http.StatusOK, structs.RepairResponse{Compliant: "NON_COMPLIANT", Repair: repair})
 }
 
 //GetRulebases returns a list of  all loaded rulebases
 func (h *Handler) GetRulebases(c echo.Context) error {
+	rbs := h.Checker.RuleBaseDescriptions()
 	//if we have no loaded rulebases return Error
-	if len(h.Checker.RuleBases) == 0 {
+	if len(rbs) == 0 {
 		return c.JSON(http.StatusNotFound, nil)
 	}
-	return c.JSON(http.StatusOK, h.Checker.RuleBases)
+	return c.JSON(http.StatusOK, rbs)
+}
+
+//PostRulebase validates, compiles and saves a new rulebase
+//
+//Context-Parameter
+// 	in RequestBody	the YAML source of the rulebase
+func (h *Handler) PostRulebase(c echo.Context) error {
+	src, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		log.Printf("Error in postRulebaseHandler while reading the request body: %s", err)
+		e := err.Error()
+		return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+	}
+	desc, err := h.Checker.AddRuleBase(src)
+	if err != nil {
+		log.Printf("Error in postRulebaseHandler while adding the rulebase: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	return c.JSON(http.StatusCreated, desc)
+}
+
+//Playground checks a document against an unsaved rulebase, so that modelers can try out
+//changes of a rulebase without saving it. The loaded rulebases are not changed. The
+//response contains the errors and warnings of the rulebase with their line numbers
+//and, if it is valid, the verdict, explanation and inference statistics.
+//
+//Context-Parameter
+// 	in RequestBody	the rulebase source and the document or the id of a document in the database,
+//					and optionally the format of the argument graph to return, one of graphml, dot or yaml
+func (h *Handler) Playground(c echo.Context) error {
+	req := new(structs.PlaygroundRequest)
+	if err := c.Bind(req); err != nil {
+		log.Printf("Error in playgroundHandler while trying to bind request to struct: %s", err)
+		e := err.Error()
+		return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+	}
+	if req.RuleBase == "" || (req.Document == nil && req.DocumentID == "") {
+		e := "The request must contain a rulebase and a document or document id"
+		return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+	}
+	if _, ok := carneades.GraphContentTypes[req.Graph]; req.Graph != "" && !ok {
+		e := fmt.Sprintf("Unsupported graph format: %s", req.Graph)
+		return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+	}
+
+	var doc structs.Document
+	if req.Document != nil {
+		doc = *req.Document
+	} else {
+		var err error
+		doc, err = h.Db.GetDocument(req.DocumentID)
+		if err != nil {
+			log.Printf("Error in playgroundHandler while trying to get document from database: %s", err)
+			e := err.Error()
+			switch t := err.(type) {
+			case structs.HTTPError:
+				return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+			default:
+				return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+			}
+		}
+	}
+	normalizer, err := carneades.NewNormalizer(doc, h.Db, h.Taxonomies)
+	if err != nil {
+		log.Printf("Error in playgroundHandler while trying to normalize document : %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	normDoc, err := normalizer.GetNormalized()
+	if err != nil {
+		log.Printf("Error in playgroundHandler while normalizing: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.ValidationErrors:
+			return c.JSON(http.StatusUnprocessableEntity, structs.ValidationResponse{Ok: false, Reason: &e, Errors: t})
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	result, err := h.Checker.Playground([]byte(req.RuleBase), normDoc, req.Graph)
+	if err != nil {
+		log.Printf("Error in playgroundHandler while checking the document: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	if !result.Lint.Valid {
+		return c.JSON(http.StatusBadRequest, result)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+//ValidateRulebase lints a rulebase without saving it, returning the errors and
+//warnings found. The rulebase must not have the id of another loaded rulebase.
+//
+//Context-Parameter
+// 	in RequestBody	the YAML source of the rulebase
+//	baseid			query parameter, the id of the rulebase the source is a new version of (optional)
+func (h *Handler) ValidateRulebase(c echo.Context) error {
+	src, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		log.Printf("Error in validateRulebaseHandler while reading the request body: %s", err)
+		e := err.Error()
+		return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+	}
+	return c.JSON(http.StatusOK, h.Checker.LintRuleBase(src, c.QueryParam("baseid")))
+}
+
+//PutRulebase validates and compiles a new version of a rulebase and replaces the current version.
+//
+//Context-Parameter
+//	baseid			the id of the rulebase
+// 	in RequestBody	the YAML source of the rulebase
+func (h *Handler) PutRulebase(c echo.Context) error {
+	src, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		log.Printf("Error in putRulebaseHandler while reading the request body: %s", err)
+		e := err.Error()
+		return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+	}
+	desc, err := h.Checker.PutRuleBase(c.Param("baseid"), src)
+	if err != nil {
+		log.Printf("Error in putRulebaseHandler while replacing the rulebase: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusBadRequest, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	return c.JSON(http.StatusOK, desc)
+}
+
+//DeleteRulebase unloads a rulebase and deletes its file
+//
+//Context-Parameter
+//	baseid		the id of the rulebase
+func (h *Handler) DeleteRulebase(c echo.Context) error {
+	if err := h.Checker.DeleteRuleBase(c.Param("baseid")); err != nil {
+		log.Printf("Error in deleteRulebaseHandler: %s", err)
+		e := err.Error()
+		switch t := err.(type) {
+		case structs.HTTPError:
+			return c.JSON(t.Status, structs.Response{Ok: false, Reason: &e})
+		default:
+			return c.JSON(http.StatusNotFound, structs.Response{Ok: false, Reason: &e})
+		}
+	}
+	return c.JSON(http.StatusOK, structs.Response{Ok: true})
 }
@@ -0,0 +1,28 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package taxonomies
+
+import (
+	"net/http"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/taxonomy"
+	"github.com/labstack/echo"
+)
+
+//Handler ...
+type Handler struct {
+	Taxonomies *taxonomy.Service
+}
+
+//GetTaxonomy returns the taxonomy of a locale. If there is no taxonomy for the locale, the
+//taxonomy of the default locale is returned; the Content-Language header is the locale of the
+//returned taxonomy.
+//
+//Context-Parameter
+//	locale		the locale of the taxonomy, e.g. en or de
+func (h *Handler) GetTaxonomy(c echo.Context) error {
+	tax, locale := h.Taxonomies.Get(c.Param("locale"))
+	c.Response().Header().Set("Content-Language", locale)
+	return c.JSON(http.StatusOK, tax)
+}
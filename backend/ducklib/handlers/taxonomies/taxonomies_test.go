@@ -0,0 +1,60 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package taxonomies
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+	"github.com/Microsoft/DUCK/backend/ducklib/taxonomy"
+	"github.com/labstack/echo"
+)
+
+func TestHandler_GetTaxonomy(t *testing.T) {
+	taxonomies := map[string]structs.Taxonomy{}
+	for locale, value := range map[string]string{"en": "this capability", "de": "diese Funktion"} {
+		var tax structs.Taxonomy
+		if err := json.Unmarshal([]byte(`{"scope": [{"value": "`+value+`", "code": "capability", "category": "1"}]}`), &tax); err != nil {
+			t.Fatal(err)
+		}
+		taxonomies[locale] = tax
+	}
+	s, err := taxonomy.New(taxonomies, taxonomy.DefaultLocale)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	h := Handler{Taxonomies: s}
+
+	tests := []struct {
+		locale     string
+		wantLocale string
+		wantValue  string
+	}{
+		{"de", "de", "diese Funktion"},
+		{"ko", "en", "this capability"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/v1/taxonomies/"+tt.locale, nil), rec)
+		c.SetParamNames("locale")
+		c.SetParamValues(tt.locale)
+		if err := h.GetTaxonomy(c); err != nil {
+			t.Fatalf("%q. GetTaxonomy() error = %v", tt.locale, err)
+		}
+		var got structs.Taxonomy
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%q. GetTaxonomy() body = %s: %v", tt.locale, rec.Body, err)
+		}
+		if len(got["scope"]) != 1 {
+			t.Fatalf("%q. GetTaxonomy() body = %s", tt.locale, rec.Body)
+		}
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Language") != tt.wantLocale || got["scope"][0].Value != tt.wantValue {
+			t.Errorf("%q. GetTaxonomy() = %v, %v, %v, want %v, %v, %v", tt.locale, rec.Code,
+				rec.Header().Get("Content-Language"), got["scope"][0].Value, http.StatusOK, tt.wantLocale, tt.wantValue)
+		}
+	}
+}
@@ -6,15 +6,19 @@ package ducklib
 
 import (
 	"log"
+	"time"
 
 	"github.com/Microsoft/DUCK/backend/ducklib/carneades"
 	"github.com/Microsoft/DUCK/backend/ducklib/config"
 	"github.com/Microsoft/DUCK/backend/ducklib/db"
+	"github.com/Microsoft/DUCK/backend/ducklib/handlers/assumptions"
 	"github.com/Microsoft/DUCK/backend/ducklib/handlers/dictionaries"
 	"github.com/Microsoft/DUCK/backend/ducklib/handlers/documents"
 	"github.com/Microsoft/DUCK/backend/ducklib/handlers/rulebases"
+	"github.com/Microsoft/DUCK/backend/ducklib/handlers/taxonomies"
 	"github.com/Microsoft/DUCK/backend/ducklib/handlers/users"
 	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+	"github.com/Microsoft/DUCK/backend/ducklib/taxonomy"
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
 )
@@ -47,10 +51,27 @@ func GetServer(conf config.Configuration) *echo.Echo {
 	if err != nil {
 		panic(err)
 	}
+	if conf.Search != nil {
+		checker.SetSearchOptions(carneades.SearchOptions{
+			Workers:        conf.Search.Workers,
+			MaxEvaluations: conf.Search.MaxEvaluations,
+			Timeout:        time.Duration(conf.Search.Timeout) * time.Second,
+		})
+	}
 	err = checker.Intialize()
 	if err != nil {
 		panic(err)
 	}
+	if conf.RulebaseWatch > 0 {
+		go checker.Watch(time.Duration(conf.RulebaseWatch)*time.Second, nil)
+	}
+
+	log.Printf("Taxonomy directory: %s", conf.TaxonomyDir)
+
+	tax, err := taxonomy.Load(conf.TaxonomyDir, taxonomy.DefaultLocale)
+	if err != nil {
+		panic(err)
+	}
 
 	//New echo instance
 	e := echo.New()
@@ -88,7 +109,7 @@ func GetServer(conf config.Configuration) *echo.Echo {
 	users.DELETE("/:id/dictionary/:code", dih.DeleteDictItem, jwtMiddleware) //delete a dictonary entry
 
 	//data use statement document resources
-	doh := documents.Handler{Db: datab}
+	doh := documents.Handler{Db: datab, Taxonomies: tax}
 	documents := api.Group("/documents", jwtMiddleware)    //base URI
 	documents.POST("", doh.PostDoc)                        //create document
 	documents.PUT("", doh.PutDoc)                          //update document
@@ -96,16 +117,44 @@ func GetServer(conf config.Configuration) *echo.Echo {
 	documents.GET("/:userid/summary", doh.GetDocSummaries) //return document summaries for the author
 	documents.GET("/:docid", doh.GetDoc)                   //return document
 	documents.POST("/copy/:docid", doh.CopyStatements)     //copies the statements from an existing Document to a new one
+	documents.POST("/validate", doh.Validate)              //normalize a document and report all problems of its statements
 
 	//rulebase resources
-	ruh := rulebases.Handler{Db: datab, WebDir: conf.WebDir, Checker: checker}
-	rulebases := api.Group("/rulebases", jwtMiddleware) //base URI
-	rulebases.GET("", ruh.GetRulebases)                 //Returns a dictionary with all available Rulebases
-	//rulebases.POST("/", postRsHandler)                                //create a rulebase
-	//rulebases.DELETE("/:id", deleteRsHandler)                         //delete a rulebase
-	//rulebases.PUT("/:setid", putRsHandler)                            //update a rulebase
-	rulebases.PUT("/:baseid/documents", ruh.CheckDoc)               //process provided document against rulebase
-	rulebases.PUT("/:baseid/documents/:documentid", ruh.CheckDocID) //process document against rulebase
+	ruh := rulebases.Handler{Db: datab, Taxonomies: tax, Checker: checker}
+	rulebases := api.Group("/rulebases", jwtMiddleware)                            //base URI
+	rulebases.GET("", ruh.GetRulebases)                                            //Returns a dictionary with all available Rulebases
+	rulebases.POST("", ruh.PostRulebase)                                           //create a rulebase
+	rulebases.POST("/validate", ruh.ValidateRulebase)                              //lint a rulebase without saving it
+	rulebases.POST("/playground", ruh.Playground)                                  //check a document against a rulebase without saving it
+	rulebases.DELETE("/:baseid", ruh.DeleteRulebase)                               //delete a rulebase
+	rulebases.PUT("/:baseid", ruh.PutRulebase)                                     //update a rulebase
+	rulebases.PUT("/:baseid/impact", ruh.Impact)                                   //report the documents whose results change with a candidate rulebase
+	rulebases.PUT("/:baseid/documents", ruh.CheckDoc)                              //process provided document against rulebase
+	rulebases.PUT("/:baseid/documents/:documentid", ruh.CheckDocID)                //process document against rulebase
+	rulebases.GET("/:baseid/documents/:documentid/graph", ruh.GetGraph)            //return the argument graph of the document
+	rulebases.PUT("/:baseid/documents/:documentid/graph", ruh.GetGraph)            //return the argument graph of the document
+	rulebases.POST("/:baseid/documents/:documentid/query", ruh.Query)              //return the statements of the argument graph matching a term pattern
+	rulebases.PUT("/:baseid/documents/:documentid/session", ruh.Session)           //recheck an edited document incrementally
+	rulebases.PUT("/:baseid/documents/:documentid/alternatives", ruh.Alternatives) //stream compliant variants of the document
+	rulebases.PUT("/:baseid/documents/:documentid/repair", ruh.Repair)             //propose the cheapest edits making the document compliant
+
+	//compliance of documents with several rulebases and its history
+	documents.PUT("/compliance", ruh.CheckDocAll)                         //process provided document against all rulebases
+	documents.PUT("/:docid/compliance", ruh.CheckDocIDAll)                //process document against all rulebases
+	documents.GET("/:docid/compliance-history", doh.GetComplianceHistory) //return the results of past compliance checks of the document
+
+	//assumption set resources
+	ash := assumptions.Handler{Db: datab}
+	assumptionSets := api.Group("/assumption-sets", jwtMiddleware) //base URI
+	assumptionSets.GET("", ash.GetAssumptionSets)                  //return all assumption sets
+	assumptionSets.POST("", ash.PostAssumptionSet)                 //create an assumption set
+	assumptionSets.GET("/:setid", ash.GetAssumptionSet)            //return an assumption set
+	assumptionSets.PUT("/:setid", ash.PutAssumptionSet)            //update an assumption set
+	assumptionSets.DELETE("/:setid", ash.DeleteAssumptionSet)      //delete an assumption set
+
+	//taxonomy resources, which are needed before the login
+	txh := taxonomies.Handler{Taxonomies: tax}
+	api.GET("/taxonomies/:locale", txh.GetTaxonomy) //return the taxonomy of a locale
 
 	// serves the static files
 	wbd := conf.WebDir
@@ -6,6 +6,9 @@ package structs
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/labstack/echo"
@@ -19,6 +22,13 @@ type DBConf struct {
 	Name     string `json:"name,omitempty`
 }
 
+//SearchConf bounds the search for compliant alternatives of a document
+type SearchConf struct {
+	Workers        int `json:"workers,omitempty"`        // number of compliance checks run in parallel
+	MaxEvaluations int `json:"maxevaluations,omitempty"` // maximum number of compliance checks, 0 for no limit
+	Timeout        int `json:"timeout,omitempty"`        // maximum duration of a search in seconds, 0 for no limit
+}
+
 type User struct {
 	ID               string     `json:"id"`
 	Email            string     `json:"email"`
@@ -46,25 +56,60 @@ type Document struct {
 	Dictionary    Dictionary  `json:"dictionary"`
 }
 
+//AssumptionSet is a named collection of ground facts, e.g. li(...) for a stated legitimate interest
+//or isInEu(custom_region), which are assumed when checking the documents referencing the set.
+//The AssumptionSet field of a document references the set assumed for this document,
+//the AssumptionSet field of a user the set assumed for the documents of the user by default.
+//The Owner field is a foreign key to a User.ID
+type AssumptionSet struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Owner       string   `json:"owner"`
+	Revision    string   `json:"revision"`
+	Facts       []string `json:"facts"`
+}
+
 //UserIsOwner checks if the user ID from the JWT in the context object is the same as the user ID in the Owner field of this document
 func (d *Document) IsUserOwner(c echo.Context) error {
 
+	id, err := UserID(c)
+	if err != nil {
+		return err
+	}
+	if id == d.Owner {
+		return nil
+	}
+	return NewHTTPError("User ID is not Owner ID", 401)
+}
+
+//IsUserOwner checks if the user ID from the JWT in the context object is the same as the user ID in the Owner field of this assumption set
+func (s *AssumptionSet) IsUserOwner(c echo.Context) error {
+	id, err := UserID(c)
+	if err != nil {
+		return err
+	}
+	if id == s.Owner {
+		return nil
+	}
+	return NewHTTPError("User ID is not Owner ID", 401)
+}
+
+//UserID returns the user ID from the JWT in the context object
+func UserID(c echo.Context) (string, error) {
 	user, ok := c.Get("user").(*jwt.Token)
 	if !ok {
-		return NewHTTPError("Could not access jwt", 401)
+		return "", NewHTTPError("Could not access jwt", 401)
 	}
 	claims, ok := user.Claims.(jwt.MapClaims)
 	if !ok {
-		return NewHTTPError("Could not convert jwt", 401)
+		return "", NewHTTPError("Could not convert jwt", 401)
 	}
 	id, ok := claims["id"].(string)
 	if !ok {
-		return NewHTTPError("Could not access user ID from JWT", 401)
-	}
-	if id == d.Owner {
-		return nil
+		return "", NewHTTPError("Could not access user ID from JWT", 401)
 	}
-	return NewHTTPError("User ID is not Owner ID", 401)
+	return id, nil
 }
 
 //A Statement struct represents one Statement in a document
@@ -148,9 +193,51 @@ type Response struct {
 	Documents *[]Document `json:"documents,omitempty"`
 }
 
+//ComplianceResponse contains the verdict of checking a document against a rulebase, COMPLIANT, NON_COMPLIANT or UNDECIDED.
+//If the verdict is UNDECIDED, Issues lists the unresolved issues of the rulebase causing it.
 type ComplianceResponse struct {
 	Compliant   string      `json:"compliant"`
 	Explanation interface{} `json:"explanation"`
+	Issues      interface{} `json:"issues,omitempty"`
+	Reason      *string     `json:"reason,omitempty"` // why the document could not be checked, if it could not
+}
+
+//QueryRequest contains a term pattern with variables, e.g. compatiblePurpose(S1,S2),
+//to be matched against the statements of the argument graph of a document
+type QueryRequest struct {
+	Query string `json:"query"`
+}
+
+//PlaygroundRequest contains the YAML source of an unsaved rulebase and the document to check
+//against it, either the document itself or the id of a document in the database
+type PlaygroundRequest struct {
+	RuleBase   string    `json:"rulebase"`
+	Document   *Document `json:"document,omitempty"`
+	DocumentID string    `json:"documentId,omitempty"`
+	Graph      string    `json:"graph,omitempty"` // the format of the argument graph to return, if any
+}
+
+//MultiComplianceResponse contains the results of checking a document against several rulebases, keyed by rulebase id
+type MultiComplianceResponse map[string]ComplianceResponse
+
+//ComplianceRecord is the persisted result of checking a revision of a document against a rulebase.
+//The DocumentID field is a foreign key to a Document.ID, the User field to a User.ID
+type ComplianceRecord struct {
+	ID               string      `json:"id"`
+	DocumentID       string      `json:"documentId"`
+	DocumentRevision string      `json:"documentRevision"`
+	RuleBaseID       string      `json:"rulebaseId"`
+	RuleBaseVersion  string      `json:"rulebaseVersion"`
+	RuleBaseRevision string      `json:"rulebaseRevision"` // hash of the content of the rulebase file
+	Compliant        string      `json:"compliant"`
+	Explanation      interface{} `json:"explanation"`
+	Timestamp        time.Time   `json:"timestamp"`
+	User             string      `json:"user"`
+}
+
+type RepairResponse struct {
+	Compliant string      `json:"compliant"`
+	Repair    interface{} `json:"repair"`
 }
 
 type Login struct {
@@ -164,12 +251,71 @@ type Rulebase struct {
 	Revision string `json:"_rev"`
 }
 
-//
-type Taxonomy map[string][]struct {
-	Value    string `json:"value"`
-	Code     string `json:"code"`
-	Category string `json:"category"`
-	Fixed    bool   `json:"fixed"`
+//Taxonomy maps the types of codes, e.g. scope or dataCategory, to their entries
+type Taxonomy map[string][]TaxonomyEntry
+
+//TaxonomyEntry is a code of a taxonomy. The category numbers the entries of a type
+//hierarchically, e.g. 2.1 is a kind of 2. Scopes list the narrower scopes they include
+//and the scopes they are equivalent to, locations the regions they belong to, e.g. eu.
+type TaxonomyEntry struct {
+	Value      string   `json:"value"`
+	Code       string   `json:"code"`
+	Category   string   `json:"category"`
+	Fixed      bool     `json:"fixed"`
+	Includes   []string `json:"includes,omitempty"`
+	Equivalent []string `json:"equivalent,omitempty"`
+	Regions    []string `json:"regions,omitempty"`
+}
+
+//IsAtom reports whether the code is read as an atom by the term parsers of the compliance
+//checker, i.e. a name starting with a lowercase letter other than true and false, so that
+//it can be used in terms without encoding it
+func IsAtom(code string) bool {
+	if code == "" || code[0] < 'a' || code[0] > 'z' || code == "true" || code == "false" {
+		return false
+	}
+	for i := 1; i < len(code); i++ {
+		c := code[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+//Codes of the validation errors of statements
+const (
+	ValidationMissingScope  = "missing_scope"         // the statement has none of the scope fields
+	ValidationMissingField  = "missing_field"         // a required field of the statement is empty
+	ValidationAmbiguousCode = "ambiguous_custom_code" // a custom code is a different standard code than in another statement
+)
+
+//ValidationError is a problem of a statement which keeps the document from being normalized.
+//Statement is the index of the statement in the document and Field the JSON name of the field, e.g. actionCode.
+type ValidationError struct {
+	TrackingID string `json:"trackingId"`
+	Statement  int    `json:"statement"`
+	Field      string `json:"field"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+//ValidationErrors are all problems found when normalizing a document
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, v := range e {
+		msgs[i] = fmt.Sprintf("statement %s: %s", v.TrackingID, v.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+//ValidationResponse is the report of validating a document, listing all problems of its statements
+type ValidationResponse struct {
+	Ok     bool             `json:"ok"`
+	Reason *string          `json:"reason,omitempty"`
+	Errors ValidationErrors `json:"errors"`
 }
 
 // HTTPError is an error with an http statuscode, it can also wrap another underlying error
@@ -60,3 +60,47 @@ func TestHTTPError_Error(t *testing.T) {
 		}
 	}
 }
+
+func TestValidationErrors_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		e    ValidationErrors
+		want string
+	}{
+		{"one", ValidationErrors{{TrackingID: "s0", Message: "statement is missing data use field"}},
+			"statement s0: statement is missing data use field"},
+		{"two", ValidationErrors{{TrackingID: "s0", Message: "a"}, {TrackingID: "s1", Message: "b"}},
+			"statement s0: a; statement s1: b"},
+	}
+	for _, tt := range tests {
+		if got := tt.e.Error(); got != tt.want {
+			t.Errorf("%q. ValidationErrors.Error() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsAtom(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"customer_content", true},
+		{"de", true},
+		{"in", true},
+		{"is", true},
+		{"x1_Y", true},
+		{"", false},
+		{"true", false},
+		{"false", false},
+		{"Customer", false},
+		{"_c", false},
+		{"1a", false},
+		{"a-b", false},
+		{"a b", false},
+	}
+	for _, tt := range tests {
+		if got := IsAtom(tt.code); got != tt.want {
+			t.Errorf("IsAtom(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
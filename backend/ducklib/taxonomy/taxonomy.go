@@ -0,0 +1,250 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package taxonomy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+)
+
+//DefaultLocale is the locale whose taxonomy is used for documents of a locale without a taxonomy
+const DefaultLocale = "en"
+
+//Service holds the taxonomies of all locales, which are loaded once and then only read
+type Service struct {
+	taxonomies    map[string]structs.Taxonomy
+	defaultLocale string
+	facts         []string
+}
+
+//Load reads the taxonomies of all locales from the files taxonomy-<locale>.json in the directory
+//and validates them, see New
+func Load(dir string, defaultLocale string) (*Service, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "taxonomy-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	taxonomies := make(map[string]structs.Taxonomy, len(files))
+	for _, file := range files {
+		locale := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "taxonomy-"), ".json")
+		dat, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var tax structs.Taxonomy
+		if err := json.Unmarshal(dat, &tax); err != nil {
+			return nil, fmt.Errorf("taxonomy %s: %s", file, err)
+		}
+		taxonomies[locale] = tax
+	}
+	return New(taxonomies, defaultLocale)
+}
+
+//New returns a service for the taxonomies of the locales. The taxonomies are only translations
+//of each other, so each of them has to define the same codes as the taxonomy of the default
+//locale, with the same categories, included and equivalent scopes and regions, and no code twice.
+//The codes are used as atoms of rulebases, so they have to start with a lowercase letter
+//and consist of letters, digits and underscores.
+func New(taxonomies map[string]structs.Taxonomy, defaultLocale string) (*Service, error) {
+	def, ok := taxonomies[defaultLocale]
+	if !ok {
+		return nil, fmt.Errorf("no taxonomy for the default locale %s", defaultLocale)
+	}
+	want, err := entries(def)
+	if err != nil {
+		return nil, fmt.Errorf("taxonomy %s: %s", defaultLocale, err)
+	}
+	for _, locale := range sortedLocales(taxonomies) {
+		got, err := entries(taxonomies[locale])
+		if err != nil {
+			return nil, fmt.Errorf("taxonomy %s: %s", locale, err)
+		}
+		if err := compare(got, want); err != nil {
+			return nil, fmt.Errorf("taxonomy %s differs from taxonomy %s: %s", locale, defaultLocale, err)
+		}
+	}
+	facts, err := deriveFacts(want)
+	if err != nil {
+		return nil, fmt.Errorf("taxonomy %s: %s", defaultLocale, err)
+	}
+	return &Service{taxonomies: taxonomies, defaultLocale: defaultLocale, facts: facts}, nil
+}
+
+//Get returns the taxonomy of the locale and the locale of the taxonomy. A locale with a region,
+//e.g. de-CH, falls back to the taxonomy of its language, and a locale without a taxonomy
+//to that of the default locale. The taxonomy must not be modified.
+func (s *Service) Get(locale string) (structs.Taxonomy, string) {
+	if tax, ok := s.taxonomies[locale]; ok {
+		return tax, locale
+	}
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		if tax, ok := s.taxonomies[locale[:i]]; ok {
+			return tax, locale[:i]
+		}
+	}
+	return s.taxonomies[s.defaultLocale], s.defaultLocale
+}
+
+//Facts returns the ground facts derived from the taxonomies, in sorted order, which are the
+//same for all locales. The facts must not be modified.
+//
+//	kindOf(Code,Parent)			the category of the data category or data use category Code
+//						is a subcategory of that of Parent, e.g. 2.1 of 2
+//	lesserScope(Scope,Wider)		the scope Wider includes Scope
+//	equivalentScopeCode(Scope,Other)	the scope Scope is equivalent to Other
+//	inRegion(Location,Region)		the location belongs to the region, e.g. eu
+func (s *Service) Facts() []string {
+	return s.facts
+}
+
+//Locales returns the sorted locales with a taxonomy
+func (s *Service) Locales() []string {
+	return sortedLocales(s.taxonomies)
+}
+
+func sortedLocales(taxonomies map[string]structs.Taxonomy) []string {
+	locales := make([]string, 0, len(taxonomies))
+	for locale := range taxonomies {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+//entries maps the types of a taxonomy, e.g. scope, to their entries by code
+func entries(tax structs.Taxonomy) (map[string]map[string]structs.TaxonomyEntry, error) {
+	m := make(map[string]map[string]structs.TaxonomyEntry, len(tax))
+	for typ, l := range tax {
+		m[typ] = make(map[string]structs.TaxonomyEntry, len(l))
+		for _, entry := range l {
+			if !structs.IsAtom(entry.Code) {
+				return nil, fmt.Errorf("%s code %q is not an atom", typ, entry.Code)
+			}
+			if _, ok := m[typ][entry.Code]; ok {
+				return nil, fmt.Errorf("%s code %q is defined more than once", typ, entry.Code)
+			}
+			m[typ][entry.Code] = entry
+		}
+	}
+	return m, nil
+}
+
+//compare returns an error describing the first difference of the entries, in sorted order.
+//The values of the entries are translations, so they are not compared.
+func compare(got, want map[string]map[string]structs.TaxonomyEntry) error {
+	for _, typ := range sortedTypes(want) {
+		if _, ok := got[typ]; !ok {
+			return fmt.Errorf("missing type %s", typ)
+		}
+		for _, code := range sortedCodes(want[typ]) {
+			g, ok := got[typ][code]
+			if !ok {
+				return fmt.Errorf("missing %s code %q", typ, code)
+			}
+			w := want[typ][code]
+			if g.Category != w.Category {
+				return fmt.Errorf("%s code %q has category %q instead of %q", typ, code, g.Category, w.Category)
+			}
+			if !equalCodes(g.Includes, w.Includes) {
+				return fmt.Errorf("%s code %q includes %v instead of %v", typ, code, g.Includes, w.Includes)
+			}
+			if !equalCodes(g.Equivalent, w.Equivalent) {
+				return fmt.Errorf("%s code %q is equivalent to %v instead of %v", typ, code, g.Equivalent, w.Equivalent)
+			}
+			if !equalCodes(g.Regions, w.Regions) {
+				return fmt.Errorf("%s code %q has regions %v instead of %v", typ, code, g.Regions, w.Regions)
+			}
+		}
+	}
+	for _, typ := range sortedTypes(got) {
+		if _, ok := want[typ]; !ok {
+			return fmt.Errorf("additional type %s", typ)
+		}
+		for _, code := range sortedCodes(got[typ]) {
+			if _, ok := want[typ][code]; !ok {
+				return fmt.Errorf("additional %s code %q", typ, code)
+			}
+		}
+	}
+	return nil
+}
+
+func equalCodes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//kindOfTypes are the types of codes whose categories are derived as kindOf facts. The categories
+//of the other types, e.g. scope or location, only number their entries.
+var kindOfTypes = map[string]bool{"dataCategory": true, "dataUseCategory": true}
+
+//deriveFacts returns the sorted facts of the entries of a taxonomy, see Service.Facts
+func deriveFacts(m map[string]map[string]structs.TaxonomyEntry) ([]string, error) {
+	facts := []string{}
+	for _, typ := range sortedTypes(m) {
+		byCategory := make(map[string]string, len(m[typ]))
+		for code, entry := range m[typ] {
+			byCategory[entry.Category] = code
+		}
+		for _, code := range sortedCodes(m[typ]) {
+			entry := m[typ][code]
+			if i := strings.LastIndex(entry.Category, "."); kindOfTypes[typ] && i > 0 {
+				if parent, ok := byCategory[entry.Category[:i]]; ok {
+					facts = append(facts, fmt.Sprintf("kindOf(%s,%s)", code, parent))
+				}
+			}
+			for _, narrower := range entry.Includes {
+				if _, ok := m[typ][narrower]; !ok {
+					return nil, fmt.Errorf("%s code %q includes the unknown code %q", typ, code, narrower)
+				}
+				facts = append(facts, fmt.Sprintf("lesserScope(%s,%s)", narrower, code))
+			}
+			for _, other := range entry.Equivalent {
+				if _, ok := m[typ][other]; !ok {
+					return nil, fmt.Errorf("%s code %q is equivalent to the unknown code %q", typ, code, other)
+				}
+				facts = append(facts, fmt.Sprintf("equivalentScopeCode(%s,%s)", code, other))
+			}
+			for _, region := range entry.Regions {
+				if !structs.IsAtom(region) {
+					return nil, fmt.Errorf("%s code %q has the region %q, which is not an atom", typ, code, region)
+				}
+				facts = append(facts, fmt.Sprintf("inRegion(%s,%s)", code, region))
+			}
+		}
+	}
+	sort.Strings(facts)
+	return facts, nil
+}
+
+func sortedTypes(m map[string]map[string]structs.TaxonomyEntry) []string {
+	types := make([]string, 0, len(m))
+	for typ := range m {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func sortedCodes(m map[string]structs.TaxonomyEntry) []string {
+	codes := make([]string, 0, len(m))
+	for code := range m {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
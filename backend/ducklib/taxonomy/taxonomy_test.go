@@ -0,0 +1,147 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package taxonomy
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+)
+
+// frontendTaxonomies is the directory of the taxonomies of the frontend
+var frontendTaxonomies = filepath.Join("..", "..", "..", "frontend", "src", "assets", "config")
+
+func testTaxonomy(t *testing.T, src string) structs.Taxonomy {
+	var tax structs.Taxonomy
+	if err := json.Unmarshal([]byte(src), &tax); err != nil {
+		t.Fatal(err)
+	}
+	return tax
+}
+
+func TestLoad(t *testing.T) {
+	s, err := Load(frontendTaxonomies, DefaultLocale)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got, want := s.Locales(), []string{"de", "en", "ko"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Locales() = %v, want %v", got, want)
+	}
+	facts := map[string]bool{}
+	for _, f := range s.Facts() {
+		facts[f] = true
+	}
+	for _, f := range []string{
+		"kindOf(account_data_payment,account_data)",
+		"kindOf(derived_data_user_telemetry,derived_data_user)",
+		"kindOf(market_contextual,market)",
+		"lesserScope(capability,service)",
+		"equivalentScopeCode(third_party_partners,third_party_services)",
+		"inRegion(de,eu)",
+	} {
+		if !facts[f] {
+			t.Errorf("Facts() does not contain %s", f)
+		}
+	}
+	if facts["inRegion(us,eu)"] {
+		t.Errorf("Facts() contains inRegion(us,eu)")
+	}
+	if _, err := Load(frontendTaxonomies, "fr"); err == nil {
+		t.Errorf("Load() without a taxonomy of the default locale succeeded")
+	}
+}
+
+func TestNew(t *testing.T) {
+	en := `{"scope": [{"value": "this capability", "code": "capability", "category": "1"}]}`
+	tests := []struct {
+		name    string
+		de      string
+		wantErr bool
+	}{
+		{"translation", `{"scope": [{"value": "diese Funktion", "code": "capability", "category": "1"}]}`, false},
+		{"missing code", `{"scope": []}`, true},
+		{"additional code", `{"scope": [{"code": "capability", "category": "1"}, {"code": "service", "category": "2"}]}`, true},
+		{"other category", `{"scope": [{"code": "capability", "category": "2"}]}`, true},
+		{"missing type", `{}`, true},
+		{"additional type", `{"scope": [{"code": "capability", "category": "1"}], "location": []}`, true},
+		{"other regions", `{"scope": [{"code": "capability", "category": "1", "regions": ["eu"]}]}`, true},
+		{"unknown included scope", `{"scope": [{"code": "capability", "category": "1", "includes": ["service"]}]}`, true},
+		{"code not an atom", `{"scope": [{"code": "Capability", "category": "1"}]}`, true},
+		{"duplicate code", `{"scope": [{"code": "capability", "category": "1"}, {"code": "capability", "category": "1"}]}`, true},
+	}
+	for _, tt := range tests {
+		_, err := New(map[string]structs.Taxonomy{"en": testTaxonomy(t, en), "de": testTaxonomy(t, tt.de)}, "en")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q. New() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestService_Facts(t *testing.T) {
+	en := testTaxonomy(t, `{
+		"scope": [
+			{"code": "capability", "category": "1", "equivalent": ["service"]},
+			{"code": "service", "category": "2", "includes": ["capability"]}
+		],
+		"dataCategory": [
+			{"code": "derived_data", "category": "2"},
+			{"code": "derived_data_user", "category": "2.1"},
+			{"code": "derived_data_user_telemetry", "category": "2.1.1"}
+		],
+		"dataUseCategory": [
+			{"code": "market", "category": "5"},
+			{"code": "market_contextual", "category": "5.1"}
+		],
+		"location": [
+			{"code": "eu", "category": "1", "regions": ["eu"]},
+			{"code": "de", "category": "1.1", "regions": ["eu"]},
+			{"code": "us", "category": "4.2"}
+		]
+	}`)
+	s, err := New(map[string]structs.Taxonomy{"en": en}, "en")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	want := []string{
+		"equivalentScopeCode(capability,service)",
+		"inRegion(de,eu)",
+		"inRegion(eu,eu)",
+		"kindOf(derived_data_user,derived_data)",
+		"kindOf(derived_data_user_telemetry,derived_data_user)",
+		"kindOf(market_contextual,market)",
+		"lesserScope(capability,service)",
+	}
+	if got := s.Facts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Facts() = %v, want %v", got, want)
+	}
+}
+
+func TestService_Get(t *testing.T) {
+	en := testTaxonomy(t, `{"scope": [{"value": "this capability", "code": "capability", "category": "1"}]}`)
+	de := testTaxonomy(t, `{"scope": [{"value": "diese Funktion", "code": "capability", "category": "1"}]}`)
+	s, err := New(map[string]structs.Taxonomy{"en": en, "de": de}, "en")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tests := []struct {
+		locale     string
+		want       structs.Taxonomy
+		wantLocale string
+	}{
+		{"de", de, "de"},
+		{"en", en, "en"},
+		{"de-CH", de, "de"},
+		{"ko", en, "en"},
+		{"", en, "en"},
+	}
+	for _, tt := range tests {
+		got, locale := s.Get(tt.locale)
+		if !reflect.DeepEqual(got, tt.want) || locale != tt.wantLocale {
+			t.Errorf("Get(%q) = %v, %v, want %v, %v", tt.locale, got, locale, tt.want, tt.wantLocale)
+		}
+	}
+}
@@ -4,6 +4,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -48,6 +49,15 @@ func main() {
 	// create config
 	conf := config.NewConfiguration(confPath)
 
+	//run a subcommand instead of the server, e.g. duck rulebase lint rb.yml
+	if args := flag.Args(); len(args) > 0 {
+		if args[0] != "rulebase" {
+			fmt.Printf("unknown command: %s\n", args[0])
+			os.Exit(2)
+		}
+		os.Exit(rulebaseCommand(args[1:], conf, os.Stdout))
+	}
+
 	//set routes
 	//	e := ducklib.GetServer(webDir, []byte(jwtKey), ruleBaseDir)
 	e := ducklib.GetServer(conf)
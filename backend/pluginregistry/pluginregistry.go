@@ -19,12 +19,22 @@ type DBPlugin interface {
 	UpdateUserDict(dict structs.Dictionary, userID string) error
 
 	GetDocumentSummariesForUser(userid string) ([]structs.Document, error)
+	GetAllDocuments() ([]structs.Document, error)
 
 	GetDocument(id string) (structs.Document, error)
 	NewDocument(doc structs.Document) error
 	UpdateDocument(doc structs.Document) error
 	DeleteDocument(id string) error
 
+	GetAssumptionSets() ([]structs.AssumptionSet, error)
+	GetAssumptionSet(id string) (structs.AssumptionSet, error)
+	NewAssumptionSet(set structs.AssumptionSet) error
+	UpdateAssumptionSet(set structs.AssumptionSet) error
+	DeleteAssumptionSet(id string) error
+
+	NewComplianceRecord(record structs.ComplianceRecord) error
+	GetComplianceHistory(documentID string, ruleBaseID string) ([]structs.ComplianceRecord, error)
+
 	//	GetRulebase(id string) (document map[string]interface{}, err error)
 	//	NewRulebase(id string, entry string) error
 	//	UpdateRulebase(id string, entry string) error
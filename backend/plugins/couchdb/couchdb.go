@@ -34,6 +34,8 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	neturl "net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -47,6 +49,9 @@ import (
 //not using default Client: https://medium.com/@nate510/don-t-use-go-s-default-http-client-4804cb19f779
 var netClient = &http.Client{Timeout: time.Second * 10}
 
+//timestampFormat is RFC 3339 with a fixed number of fractional digits
+const timestampFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
 //Couchbase implements the pluginregistry.DBPlugin interface for CouchDB
 type Couchbase struct {
 	url      string
@@ -197,6 +202,34 @@ func (cb *Couchbase) GetDocumentSummariesForUser(userid string) ([]structs.Docum
 
 }
 
+//GetAllDocuments returns all data use documents of all users, ordered by ID
+func (cb *Couchbase) GetAllDocuments() ([]structs.Document, error) {
+	url := fmt.Sprintf("%s/%s/_design/app/_view/documents", cb.url, cb.database)
+
+	bdy, err := cb.doGet(url)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := []structs.Document{}
+	rows, err := getRows(bdy)
+	if err != nil {
+		if err.Error() == "No Data returned" {
+			return documents, nil
+		}
+		return nil, err
+	}
+
+	for _, intf := range rows {
+		row := intf.(map[string]interface{})
+		if value, ok := row["value"].(map[string]interface{}); ok {
+			documents = append(documents, docFromValueMap(value))
+		}
+	}
+
+	return documents, nil
+}
+
 // DeleteDocument deletes a Data Use Document from the Couchbase Database
 func (cb *Couchbase) DeleteDocument(id string) error {
 
@@ -301,6 +334,152 @@ func (cb *Couchbase) UpdateDocument(doc structs.Document) error {
 	return cb.putDocument(doc)
 }
 
+//GetAssumptionSets returns all assumption sets in the Couchbase Database, ordered by ID
+func (cb *Couchbase) GetAssumptionSets() ([]structs.AssumptionSet, error) {
+	url := fmt.Sprintf("%s/%s/_design/app/_view/assumption_sets", cb.url, cb.database)
+
+	bdy, err := cb.doGet(url)
+	if err != nil {
+		return nil, err
+	}
+
+	sets := []structs.AssumptionSet{}
+	rows, err := getRows(bdy)
+	if err != nil {
+		if err.Error() == "No Data returned" {
+			return sets, nil
+		}
+		return nil, err
+	}
+
+	for _, intf := range rows {
+		row := intf.(map[string]interface{})
+		if value, ok := row["value"].(map[string]interface{}); ok {
+			sets = append(sets, assumptionSetFromValueMap(value))
+		}
+	}
+
+	return sets, nil
+}
+
+//GetAssumptionSet returns the assumption set with the specified ID from the Couchbase Database
+func (cb *Couchbase) GetAssumptionSet(id string) (structs.AssumptionSet, error) {
+	mp, err := cb.getCouchbaseDocument(id)
+	if err != nil {
+		return structs.AssumptionSet{}, err
+	}
+	if getFieldValue(mp, "type") != "assumptionSet" {
+		return structs.AssumptionSet{}, structs.NewHTTPError("Assumption Set not found", http.StatusNotFound)
+	}
+
+	return assumptionSetFromValueMap(mp), nil
+}
+
+//NewAssumptionSet creates a new assumption set in the couchbase Database
+func (cb *Couchbase) NewAssumptionSet(set structs.AssumptionSet) error {
+	return cb.putAssumptionSet(set)
+}
+
+//UpdateAssumptionSet replaces an existing assumption set in the Couchbase database
+func (cb *Couchbase) UpdateAssumptionSet(set structs.AssumptionSet) error {
+	return cb.putAssumptionSet(set)
+}
+
+//DeleteAssumptionSet deletes an assumption set from the Couchbase Database
+func (cb *Couchbase) DeleteAssumptionSet(id string) error {
+	set, err := cb.GetAssumptionSet(id)
+	if err != nil {
+		return err
+	}
+
+	return cb.deleteCbDocument(id, set.Revision)
+}
+
+func (cb *Couchbase) putAssumptionSet(set structs.AssumptionSet) error {
+	entryMap := make(map[string]interface{})
+	entryMap["type"] = "assumptionSet"
+	entryMap["_id"] = set.ID
+	entryMap["name"] = set.Name
+	entryMap["description"] = set.Description
+	entryMap["owner"] = set.Owner
+	if set.Revision != "" {
+		entryMap["_rev"] = set.Revision
+	}
+	facts := set.Facts
+	if facts == nil {
+		facts = []string{}
+	}
+	entryMap["facts"] = facts
+
+	return cb.putEntry(entryMap, false)
+}
+
+//NewComplianceRecord stores the result of a compliance check in the couchbase Database
+func (cb *Couchbase) NewComplianceRecord(record structs.ComplianceRecord) error {
+	entryMap := make(map[string]interface{})
+	entryMap["type"] = "compliance"
+	entryMap["_id"] = record.ID
+	entryMap["documentId"] = record.DocumentID
+	entryMap["documentRevision"] = record.DocumentRevision
+	entryMap["rulebaseId"] = record.RuleBaseID
+	entryMap["rulebaseVersion"] = record.RuleBaseVersion
+	entryMap["rulebaseRevision"] = record.RuleBaseRevision
+	entryMap["compliant"] = record.Compliant
+	entryMap["explanation"] = record.Explanation
+	//a fixed width timestamp, so the view sorts the records chronologically
+	entryMap["timestamp"] = record.Timestamp.UTC().Format(timestampFormat)
+	entryMap["user"] = record.User
+
+	return cb.putEntry(entryMap, false)
+}
+
+//GetComplianceHistory returns the compliance records of a data use document, oldest first.
+//If ruleBaseID is not empty, only the records of this rulebase are returned.
+func (cb *Couchbase) GetComplianceHistory(documentID string, ruleBaseID string) ([]structs.ComplianceRecord, error) {
+	startkey := []interface{}{documentID}
+	endkey := []interface{}{documentID, map[string]interface{}{}}
+	if ruleBaseID != "" {
+		startkey = []interface{}{documentID, ruleBaseID}
+		endkey = []interface{}{documentID, ruleBaseID, map[string]interface{}{}}
+	}
+	sk, err := json.Marshal(startkey)
+	if err != nil {
+		return nil, err
+	}
+	ek, err := json.Marshal(endkey)
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/%s/_design/app/_view/compliance_by_document?startkey=%s&endkey=%s",
+		cb.url, cb.database, neturl.QueryEscape(string(sk)), neturl.QueryEscape(string(ek)))
+
+	bdy, err := cb.doGet(url)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []structs.ComplianceRecord{}
+	rows, err := getRows(bdy)
+	if err != nil {
+		//a document which has never been checked has no history
+		if err.Error() == "No Data returned" {
+			return records, nil
+		}
+		return nil, err
+	}
+
+	for _, intf := range rows {
+		row := intf.(map[string]interface{})
+		if value, ok := row["value"].(map[string]interface{}); ok {
+			records = append(records, complianceRecordFromValueMap(value))
+		}
+	}
+	// the view sorts by rulebase first
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Timestamp.Before(records[j].Timestamp) })
+
+	return records, nil
+}
+
 func (cb *Couchbase) putUser(u structs.User) error {
 	entryMap := make(map[string]interface{})
 	entryMap["type"] = "user"
@@ -462,7 +641,9 @@ func (cb *Couchbase) Init(config structs.DBConf) error {
 		`"user":{"map":"function(doc) { if(doc.type =='user') {   emit(doc._id, doc);  }}"},` +
 		`"documents":{"map":"function(doc) { if(doc.type =='document') {   emit(doc._id, doc);  }}"},` +
 		`"rulebases":{"map":"function(doc) { if(doc.type =='rulebase') {   emit(doc._id, doc._rev);  }}"},` +
-		`"documents_by_user":{"map":"function(doc) { if(doc.type =='document') {   emit([doc.owner, doc._id], doc.name);  }}"}},` +
+		`"documents_by_user":{"map":"function(doc) { if(doc.type =='document') {   emit([doc.owner, doc._id], doc.name);  }}"},` +
+		`"assumption_sets":{"map":"function(doc) { if(doc.type =='assumptionSet') {   emit(doc._id, doc);  }}"},` +
+		`"compliance_by_document":{"map":"function(doc) { if(doc.type =='compliance') {   emit([doc.documentId, doc.rulebaseId, doc.timestamp], doc);  }}"}},` +
 		`"language":"javascript"}`
 
 	designMap := map[string]interface{}{"entry": designDoc}
@@ -523,12 +704,42 @@ func (cb *Couchbase) Init(config structs.DBConf) error {
 		if err != nil {
 			log.Printf("ERROR: %#+v\n", err)
 		}
+	} else if err := cb.updateDesignDoc(designDoc); err != nil {
+		log.Printf("ERROR: %#+v\n", err)
 	}
 
 	log.Println("Testextension initialized")
 	return nil
 }
 
+//updateDesignDoc replaces the design document of an existing database
+//if it lacks any of the views of designDoc, e.g. after an update of DUCK
+func (cb *Couchbase) updateDesignDoc(designDoc string) error {
+	current, err := cb.getCouchbaseDocument("_design/app")
+	if err != nil {
+		return err
+	}
+	var design map[string]interface{}
+	if err := json.Unmarshal([]byte(designDoc), &design); err != nil {
+		return structs.WrapErrWith(err, structs.NewHTTPError(err.Error(), 500))
+	}
+
+	views, _ := current["views"].(map[string]interface{})
+	missing := false
+	for name := range design["views"].(map[string]interface{}) {
+		if _, prs := views[name]; !prs {
+			missing = true
+		}
+	}
+	if !missing {
+		return nil
+	}
+
+	log.Println("Designfile is outdated. Updating now")
+	design["_rev"] = current["_rev"]
+	return cb.putEntry(design, false)
+}
+
 func (cb *Couchbase) testFileExists(id string) (bool, error) {
 	url := fmt.Sprintf("%s/%s/%s", cb.url, cb.database, id)
 
@@ -8,6 +8,7 @@ import (
 	"io"
 	"io/ioutil"
 	"strconv"
+	"time"
 
 	"github.com/Microsoft/DUCK/backend/ducklib/structs"
 )
@@ -235,3 +236,52 @@ func dictFromInterfaceMap(mp map[string]interface{}) structs.Dictionary {
 	}
 	return d
 }
+
+//complianceRecordFromValueMap fills the fields of a compliance record struct with values that
+//are Unmarshalled from JSON into a map
+func complianceRecordFromValueMap(mp map[string]interface{}) structs.ComplianceRecord {
+
+	var r structs.ComplianceRecord
+	if id, ok := mp["_id"]; ok {
+		r.ID = id.(string)
+	}
+	r.DocumentID = getFieldValue(mp, "documentId")
+	r.DocumentRevision = getFieldValue(mp, "documentRevision")
+	r.RuleBaseID = getFieldValue(mp, "rulebaseId")
+	r.RuleBaseVersion = getFieldValue(mp, "rulebaseVersion")
+	r.RuleBaseRevision = getFieldValue(mp, "rulebaseRevision")
+	r.Compliant = getFieldValue(mp, "compliant")
+	r.User = getFieldValue(mp, "user")
+	r.Explanation = mp["explanation"]
+	if ts, err := time.Parse(time.RFC3339Nano, getFieldValue(mp, "timestamp")); err == nil {
+		r.Timestamp = ts
+	}
+
+	return r
+}
+
+//assumptionSetFromValueMap fills the fields of an assumption set struct with values that
+//are Unmarshalled from JSON into a map
+func assumptionSetFromValueMap(mp map[string]interface{}) structs.AssumptionSet {
+
+	var set structs.AssumptionSet
+	if id, ok := mp["_id"]; ok {
+		set.ID = id.(string)
+	}
+	if rev, ok := mp["_rev"]; ok {
+		set.Revision = rev.(string)
+	}
+	set.Name = getFieldValue(mp, "name")
+	set.Description = getFieldValue(mp, "description")
+	set.Owner = getFieldValue(mp, "owner")
+	set.Facts = []string{}
+	if facts, ok := mp["facts"].([]interface{}); ok {
+		for _, f := range facts {
+			if fact, ok := f.(string); ok {
+				set.Facts = append(set.Facts, fact)
+			}
+		}
+	}
+
+	return set
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"sort"
 
 	"github.com/Microsoft/DUCK/backend/ducklib/structs"
 	"github.com/Microsoft/DUCK/backend/pluginregistry"
@@ -16,6 +17,8 @@ import (
 type Mock struct {
 	DataUseDocuments map[string]structs.Document
 	User             map[string]structs.User
+	AssumptionSets   map[string]structs.AssumptionSet
+	Compliance       []structs.ComplianceRecord
 }
 
 //Init initializes the Mock
@@ -28,6 +31,8 @@ func (m *Mock) Init(dbconf structs.DBConf) error {
 
 	m.User = make(map[string]structs.User)
 	m.DataUseDocuments = make(map[string]structs.Document)
+	m.AssumptionSets = make(map[string]structs.AssumptionSet)
+	m.Compliance = nil
 	_, ok := pluginregistry.DatabasePlugin.(*Mock)
 
 	if ok {
@@ -161,6 +166,16 @@ func (m *Mock) GetDocumentSummariesForUser(userid string) ([]structs.Document, e
 	return l, nil
 }
 
+//GetAllDocuments returns all documents, ordered by ID
+func (m *Mock) GetAllDocuments() ([]structs.Document, error) {
+	l := []structs.Document{}
+	for _, doc := range m.DataUseDocuments {
+		l = append(l, doc)
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].ID < l[j].ID })
+	return l, nil
+}
+
 //GetDocument returns a Document
 func (m *Mock) GetDocument(id string) (structs.Document, error) {
 	if d, prs := m.DataUseDocuments[id]; prs {
@@ -197,6 +212,75 @@ func (m *Mock) DeleteDocument(id string) error {
 	return errors.New("Cannot delete Document: Document not found")
 }
 
+//GetAssumptionSets returns all assumption sets, ordered by ID
+func (m *Mock) GetAssumptionSets() ([]structs.AssumptionSet, error) {
+	l := []structs.AssumptionSet{}
+	for _, set := range m.AssumptionSets {
+		l = append(l, set)
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].ID < l[j].ID })
+	return l, nil
+}
+
+//GetAssumptionSet returns an assumption set
+func (m *Mock) GetAssumptionSet(id string) (structs.AssumptionSet, error) {
+	if set, prs := m.AssumptionSets[id]; prs {
+		return set, nil
+	}
+	return structs.AssumptionSet{}, structs.NewHTTPError("Assumption Set not found", http.StatusNotFound)
+}
+
+//NewAssumptionSet creates a new assumption set
+func (m *Mock) NewAssumptionSet(set structs.AssumptionSet) error {
+	if _, prs := m.AssumptionSets[set.ID]; !prs {
+		m.AssumptionSets[set.ID] = set
+		return nil
+	}
+	return errors.New("Cannot create Assumption Set: Assumption Set already exists")
+}
+
+//UpdateAssumptionSet updates an assumption set
+func (m *Mock) UpdateAssumptionSet(set structs.AssumptionSet) error {
+	if _, prs := m.AssumptionSets[set.ID]; prs {
+		m.AssumptionSets[set.ID] = set
+		return nil
+	}
+	return errors.New("Cannot Update Assumption Set: Assumption Set not found")
+}
+
+//DeleteAssumptionSet deletes an assumption set
+func (m *Mock) DeleteAssumptionSet(id string) error {
+	if _, prs := m.AssumptionSets[id]; prs {
+		delete(m.AssumptionSets, id)
+		return nil
+	}
+	return errors.New("Cannot delete Assumption Set: Assumption Set not found")
+}
+
+//NewComplianceRecord stores a compliance record
+func (m *Mock) NewComplianceRecord(record structs.ComplianceRecord) error {
+	for _, r := range m.Compliance {
+		if r.ID == record.ID {
+			return errors.New("Cannot create Compliance Record: Record already exists")
+		}
+	}
+	m.Compliance = append(m.Compliance, record)
+	return nil
+}
+
+//GetComplianceHistory returns the compliance records of a document, oldest first,
+//optionally only those of one rulebase
+func (m *Mock) GetComplianceHistory(documentID string, ruleBaseID string) ([]structs.ComplianceRecord, error) {
+	l := []structs.ComplianceRecord{}
+	for _, r := range m.Compliance {
+		if r.DocumentID == documentID && (ruleBaseID == "" || r.RuleBaseID == ruleBaseID) {
+			l = append(l, r)
+		}
+	}
+	sort.SliceStable(l, func(i, j int) bool { return l[i].Timestamp.Before(l[j].Timestamp) })
+	return l, nil
+}
+
 /*
 
 	//GetStatement(id string) (document map[string]interface{}, err error)
@@ -0,0 +1,366 @@
+// Data Use Statement Compliance Checker (DUCK)
+// Copyright (c) Microsoft Corporation. All rights reserved.
+// Licensed under the MIT license.
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Microsoft/DUCK/backend/ducklib/carneades"
+	"github.com/Microsoft/DUCK/backend/ducklib/config"
+	"github.com/Microsoft/DUCK/backend/ducklib/db"
+	"github.com/Microsoft/DUCK/backend/ducklib/structs"
+	"github.com/Microsoft/DUCK/backend/ducklib/taxonomy"
+)
+
+const rulebaseUsage = `usage: duck rulebase lint <file>...
+       duck rulebase test [-junit <report.xml>] [<file>...]
+       duck rulebase impact [-json] <file>
+
+commands:
+  lint		check the rulebase files for errors
+  test		run the rulebase test files, by default all *_test.yml files in the rulebase directory
+  impact	check the documents in the database against the rulebase file and the loaded rulebase with its id
+`
+
+//rulebaseCommand runs the rulebase subcommand with the given arguments, writing
+//its output to out, and returns the exit code of the program
+func rulebaseCommand(args []string, conf config.Configuration, out io.Writer) int {
+	if len(args) < 1 {
+		fmt.Fprint(out, rulebaseUsage)
+		return 2
+	}
+	switch args[0] {
+	case "lint":
+		if len(args) < 2 {
+			fmt.Fprint(out, rulebaseUsage)
+			return 2
+		}
+		return lintCommand(args[1:], conf, out)
+	case "test":
+		return testCommand(args[1:], conf, out)
+	case "impact":
+		return impactCommand(args[1:], conf, out)
+	default:
+		fmt.Fprintf(out, "unknown rulebase command: %s\n%s", args[0], rulebaseUsage)
+		return 2
+	}
+}
+
+//lintCommand lints the rulebase files, checking that their ids are unique in the
+//RulebaseDir of the configuration. It returns 1 if any rulebase has errors.
+func lintCommand(files []string, conf config.Configuration, out io.Writer) int {
+	ids, err := carneades.RuleBaseIDs(conf.RulebaseDir)
+	if err != nil {
+		fmt.Fprintf(out, "warning: could not read the rulebase directory: %s\n", err)
+		ids = map[string]string{}
+	}
+
+	code := 0
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(out, "%s: error: %s\n", file, err)
+			code = 1
+			continue
+		}
+		// a file in the rulebase directory does not conflict with itself
+		result := carneades.LintRuleBase(src, ruleBaseFile(file, conf.RulebaseDir), ids)
+		for _, issue := range result.Issues {
+			fmt.Fprintf(out, "%s: %s\n", file, issue)
+		}
+		if !result.Valid {
+			code = 1
+		} else {
+			fmt.Fprintf(out, "%s: ok\n", file)
+		}
+	}
+	return code
+}
+
+//ruleBaseFile returns the name of the file in the rulebase directory which is the same file
+//as file, whatever path or symbolic link it is reached through, or "" if there is none
+func ruleBaseFile(file string, rbDir string) string {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return ""
+	}
+	entries, err := ioutil.ReadDir(rbDir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if other, err := os.Stat(filepath.Join(rbDir, entry.Name())); err == nil && os.SameFile(fi, other) {
+			return entry.Name()
+		}
+	}
+	return ""
+}
+
+//testCommand runs the rulebase test files against the rulebases in their directories,
+//reporting the results to out and, with the -junit flag, as JUnit XML.
+//It returns 1 if any test case fails.
+func testCommand(args []string, conf config.Configuration, out io.Writer) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(out)
+	junit := flags.String("junit", "", "write a JUnit XML report to this file")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	files := flags.Args()
+	if len(files) == 0 {
+		var err error
+		files, err = filepath.Glob(filepath.Join(conf.RulebaseDir, "*"+carneades.RuleBaseTestSuffix))
+		if err != nil || len(files) == 0 {
+			fmt.Fprintf(out, "no rulebase test files in %s\n", conf.RulebaseDir)
+			return 1
+		}
+	}
+
+	tax, err := taxonomy.Load(conf.TaxonomyDir, taxonomy.DefaultLocale)
+	if err != nil {
+		fmt.Fprintf(out, "could not load the taxonomies: %s\n", err)
+		return 1
+	}
+	normalize := func(doc structs.Document) (*carneades.NormalizedDocument, error) {
+		n, err := carneades.NewNormalizerWithDictionary(doc, nil, tax)
+		if err != nil {
+			return nil, err
+		}
+		return n.GetNormalized()
+	}
+
+	report := junitTestSuites{}
+	checkers := make(map[string]*carneades.ComplianceCheckerPlugin)
+	code := 0
+	for _, file := range files {
+		start := time.Now()
+		suite := junitTestSuite{Name: filepath.Base(file)}
+		checker, err := testChecker(checkers, filepath.Dir(file))
+		var tests *carneades.RuleBaseTestSuite
+		if err == nil {
+			tests, err = carneades.LoadRuleBaseTests(file)
+		}
+		if err != nil {
+			fmt.Fprintf(out, "%s: error: %s\n", file, err)
+			suite.Tests, suite.Errors = 1, 1
+			suite.TestCases = []junitTestCase{{Name: suite.Name, Error: &junitMessage{Message: err.Error()}}}
+			report.Suites = append(report.Suites, suite)
+			code = 1
+			continue
+		}
+
+		passed := 0
+		for _, r := range checker.RunRuleBaseTests(tests, normalize) {
+			tc := junitTestCase{Name: r.Case, ClassName: tests.RuleBase, Time: r.Duration.Seconds()}
+			switch {
+			case r.Err != nil:
+				fmt.Fprintf(out, "--- ERROR: %s: %s (%.2fs)\n    %s\n", file, r.Case, r.Duration.Seconds(), r.Err)
+				tc.Error = &junitMessage{Message: r.Err.Error()}
+				suite.Errors++
+			case len(r.Diffs) > 0:
+				fmt.Fprintf(out, "--- FAIL: %s: %s (%.2fs)\n", file, r.Case, r.Duration.Seconds())
+				details := ""
+				for _, d := range r.Diffs {
+					fmt.Fprintf(out, "    %s\n", d)
+					details += d + "\n"
+				}
+				tc.Failure = &junitMessage{Message: r.Diffs[0], Details: details}
+				suite.Failures++
+			default:
+				passed++
+			}
+			suite.TestCases = append(suite.TestCases, tc)
+		}
+		suite.Tests = len(suite.TestCases)
+		suite.Time = time.Since(start).Seconds()
+		report.Suites = append(report.Suites, suite)
+		if passed < suite.Tests {
+			code = 1
+		}
+		fmt.Fprintf(out, "%s: %d passed, %d failed, %d errors\n", file, passed, suite.Failures, suite.Errors)
+	}
+
+	if *junit != "" {
+		if err := writeJUnit(*junit, report); err != nil {
+			fmt.Fprintf(out, "could not write the JUnit report: %s\n", err)
+			return 1
+		}
+	}
+	return code
+}
+
+//testChecker returns a compliance checker for the rulebases in dir,
+//initializing it on first use
+func testChecker(checkers map[string]*carneades.ComplianceCheckerPlugin, dir string) (*carneades.ComplianceCheckerPlugin, error) {
+	if c, ok := checkers[dir]; ok {
+		return c, nil
+	}
+	c, err := carneades.MakeComplianceCheckerPlugin(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Intialize(); err != nil {
+		return nil, err
+	}
+	checkers[dir] = c
+	return c, nil
+}
+
+//impactCommand reports the documents in the database whose verdict or explanation
+//changes if the rulebase file replaces the rulebase with its id in the RulebaseDir
+//of the configuration. It returns 1 if the analysis could not be run.
+func impactCommand(args []string, conf config.Configuration, out io.Writer) int {
+	flags := flag.NewFlagSet("impact", flag.ContinueOnError)
+	flags.SetOutput(out)
+	asJSON := flags.Bool("json", false, "write the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprint(out, rulebaseUsage)
+		return 2
+	}
+	file := flags.Arg(0)
+	src, err := ioutil.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(out, "%s: error: %s\n", file, err)
+		return 1
+	}
+
+	checker, err := carneades.MakeComplianceCheckerPlugin(conf.RulebaseDir)
+	if err == nil {
+		err = checker.Intialize()
+	}
+	if err != nil {
+		fmt.Fprintf(out, "could not load the rulebases: %s\n", err)
+		return 1
+	}
+	if conf.Search != nil && conf.Search.Workers > 0 {
+		opts := carneades.DefaultSearchOptions
+		opts.Workers = conf.Search.Workers
+		checker.SetSearchOptions(opts)
+	}
+	if conf.DBConfig == nil {
+		fmt.Fprintln(out, "no database configured")
+		return 1
+	}
+	database, err := db.NewDatabase(*conf.DBConfig)
+	if err != nil {
+		fmt.Fprintf(out, "could not connect to the database: %s\n", err)
+		return 1
+	}
+	docs, err := database.GetAllDocuments()
+	if err != nil {
+		fmt.Fprintf(out, "could not read the documents: %s\n", err)
+		return 1
+	}
+	tax, err := taxonomy.Load(conf.TaxonomyDir, taxonomy.DefaultLocale)
+	if err != nil {
+		fmt.Fprintf(out, "could not load the taxonomies: %s\n", err)
+		return 1
+	}
+	normalize := func(doc structs.Document) (*carneades.NormalizedDocument, error) {
+		n, err := carneades.NewNormalizer(doc, database, tax)
+		if err != nil {
+			return nil, err
+		}
+		return n.GetNormalized()
+	}
+	report, err := checker.ImpactAnalysis(src, docs, normalize)
+	if err != nil {
+		fmt.Fprintf(out, "%s: error: %s\n", file, err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(out, "could not write the report: %s\n", err)
+			return 1
+		}
+		return 0
+	}
+	for _, d := range report.Impacts {
+		switch {
+		case d.Error != "":
+			fmt.Fprintf(out, "--- ERROR: %s (%s)\n    %s\n", d.DocumentID, d.Name, d.Error)
+			continue
+		case d.VerdictChanged():
+			fmt.Fprintf(out, "--- VERDICT: %s (%s): %s -> %s\n", d.DocumentID, d.Name, d.Before, d.After)
+		default:
+			fmt.Fprintf(out, "--- EXPLANATION: %s (%s): %s\n", d.DocumentID, d.Name, d.After)
+		}
+		for _, ch := range d.Changes {
+			fmt.Fprintf(out, "    %s.%s: %s -> %s\n", ch.TrackingID, ch.Predicate, impactValue(ch.Before), impactValue(ch.After))
+		}
+	}
+	fmt.Fprintf(out, "rulebase %s: %d documents, %d verdicts changed, %d explanations changed, %d errors\n",
+		report.RuleBaseID, report.Documents, report.VerdictChanges, report.ExplanationChanges, report.Errors)
+	return 0
+}
+
+//impactValue formats the explanation of a predicate in an impact report
+func impactValue(v *carneades.BoolValue) string {
+	if v == nil {
+		return "none"
+	}
+	s := fmt.Sprintf("%t", v.Value)
+	if v.Assumed {
+		s += " (assumed)"
+	}
+	if len(v.Statements) > 0 {
+		s += fmt.Sprintf(" %v", v.Statements)
+	}
+	return s
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      float64         `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Details string `xml:",chardata"`
+}
+
+func writeJUnit(path string, report junitTestSuites) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(f)
+	enc.Indent("", "  ")
+	return enc.Encode(report)
+}
@@ -19,18 +19,13 @@ i18NModule.service("AssumptionSetService", function ($http, $q) {
 
     this.initialize = function () {
         return $q(function (resolve, reject) {
-            resolve();  // remove this line when assumption-sets handler is implemented in backend
-
-            // uncomment when assumption-sets handler is implemented in backend:
-
-            // $http.get('assumption-sets').success(function (data) {
-            //     context.assumptionSets = data;
-            //     resolve();
-            // }).error(function (data, status) {
-            //     reject(status);
-            // });
-
-            // end assumption-sets handler comment
+            // the stored sets require a login, so the default set is kept if they cannot be read
+            $http.get('/v1/assumption-sets', {customErrorHandling: true}).success(function (data) {
+                context.assumptionSets = context.assumptionSets.slice(0, 1).concat(data || []);
+                resolve();
+            }).error(function () {
+                resolve();
+            });
         });
     }
 });
\ No newline at end of file
@@ -27,7 +27,7 @@ editorModule.service("DocumentModel", function(
   this.dirty = false;
 
   /**
-   * The state of the current document: NOT_VALIDATED; NON_COMPLIANT; UNKNOWN; or COMPLIANT
+   * The state of the current document: NOT_VALIDATED; NON_COMPLIANT; UNDECIDED; UNKNOWN; or COMPLIANT
    */
   this.state = "NOT_VALIDATED";
 
@@ -484,7 +484,10 @@ editorModule.filter('compatibleFilter', function (ObjectUtils) {
         }
         var set = new HashSet();
         set.add(filterStatement.trackingId);
-        set.addAll(filterStatement.$$statementExplanation.compatiblePurpose);
+        var compatiblePurpose = filterStatement.$$statementExplanation.compatiblePurpose;
+        if (ObjectUtils.notNull(compatiblePurpose) && ObjectUtils.notNull(compatiblePurpose.statements)) {
+            set.addAll(compatiblePurpose.statements);
+        }
 
         var filtered = [];
         statements.forEach(function (statement) {
@@ -24,7 +24,7 @@ editorModule.service("TaxonomyService", function (LocaleService, $http, $sce, $l
         locales.forEach(function (entry) {
             var locale = entry.id;
             $log.info("Attempting to load taxonomy for locale: " + locale);
-            $http.get("assets/config/taxonomy-" + locale + ".json").success(function (data) {
+            $http.get("/v1/taxonomies/" + locale).success(function (data) {
                 var taxonomy = angular.fromJson(data);
                 context.populate(locale, "scope", taxonomy["scope"]);
                 context.populate(locale, "qualifier", taxonomy["qualifier"]);
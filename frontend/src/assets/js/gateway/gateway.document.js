@@ -179,7 +179,7 @@ gatewayModule.service('DataUseDocumentService', function (CurrentUser, Notificat
             var url = "/v1/rulebases/" + rulebaseId + "/documents";
             var documentData = context.createDocumentData(document);
 
-            // compliant values: NON_COMPLIANT; UNKNOWN; or COMPLIANT
+            // compliant values: NON_COMPLIANT; UNDECIDED; UNKNOWN; or COMPLIANT
 
             // stub for testing
 
@@ -197,25 +197,25 @@ gatewayModule.service('DataUseDocumentService', function (CurrentUser, Notificat
                     consentRequired: { value: true, assumed: false },
                     pii: { value: true, assumed: false },
                     li: { value: true, assumed: false },
-                    compatiblePurpose: [document.statements[1].trackingId]
+                    compatiblePurpose: { value: true, assumed: false, statements: [document.statements[1].trackingId] }
                 };
                 complianceResult.explanation[document.statements[1].trackingId] = {
                     consentRequired: { value: true, assumed: false },
                     pii: { value: true, assumed: true },
                     li: { value: true, assumed: true },
-                    compatiblePurpose: [document.statements[0].trackingId]
+                    compatiblePurpose: { value: true, assumed: false, statements: [document.statements[0].trackingId] }
                 };
                 complianceResult.explanation[document.statements[2].trackingId] = {
                     consentRequired: { value: true, assumed: false },
                     pii: { value: false, assumed: false },
                     li: { value: false, assumed: false },
-                    compatiblePurpose: []
+                    compatiblePurpose: { value: false, assumed: false, statements: [] }
                 };
                 complianceResult.explanation[document.statements[3].trackingId] = {
                     consentRequired: { value: false, assumed: false },
                     pii: { value: false, assumed: false },
                     li: { value: true, assumed: false },
-                    compatiblePurpose: []
+                    compatiblePurpose: { value: false, assumed: false, statements: [] }
                 };
                 NotificationService.clear();
                 resolve(context.mapExplanation(complianceResult));
@@ -256,7 +256,7 @@ gatewayModule.service('DataUseDocumentService', function (CurrentUser, Notificat
             var url = "/v1/rulebases/" + rulebaseId + "/documents";
             var complianceResult;
             // stub for testing
-            // compliant values: NON_COMPLIANT; UNKNOWN; or COMPLIANT
+            // compliant values: NON_COMPLIANT; UNDECIDED; UNKNOWN; or COMPLIANT
             /*
              if (document.statements.length <= 2) {
              complianceResult = {